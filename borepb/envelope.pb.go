@@ -0,0 +1,685 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.2
+// source: protos/envelope.proto
+
+package __
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Envelope wraps every websocket message exchanged after the hello/welcome
+// handshake, so new message types can be added without breaking peers.
+type Envelope struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*Envelope_Request
+	//	*Envelope_Response
+	//	*Envelope_Error
+	//	*Envelope_Cancel
+	//	*Envelope_Stats
+	//	*Envelope_ConfigUpdate
+	//	*Envelope_Shutdown
+	//	*Envelope_Blocked
+	//	*Envelope_Frame
+	Message       isEnvelope_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	mi := &file_protos_envelope_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_envelope_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_protos_envelope_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Envelope) GetMessage() isEnvelope_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *Envelope) GetRequest() *Request {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Request); ok {
+			return x.Request
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetResponse() *Response {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Response); ok {
+			return x.Response
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetError() *Error {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetCancel() *Cancel {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Cancel); ok {
+			return x.Cancel
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetStats() *Stats {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Stats); ok {
+			return x.Stats
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetConfigUpdate() *ConfigUpdate {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_ConfigUpdate); ok {
+			return x.ConfigUpdate
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetShutdown() *Shutdown {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Shutdown); ok {
+			return x.Shutdown
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetBlocked() *Blocked {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Blocked); ok {
+			return x.Blocked
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetFrame() *Frame {
+	if x != nil {
+		if x, ok := x.Message.(*Envelope_Frame); ok {
+			return x.Frame
+		}
+	}
+	return nil
+}
+
+type isEnvelope_Message interface {
+	isEnvelope_Message()
+}
+
+type Envelope_Request struct {
+	Request *Request `protobuf:"bytes,1,opt,name=request,proto3,oneof"`
+}
+
+type Envelope_Response struct {
+	Response *Response `protobuf:"bytes,2,opt,name=response,proto3,oneof"`
+}
+
+type Envelope_Error struct {
+	Error *Error `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
+}
+
+type Envelope_Cancel struct {
+	Cancel *Cancel `protobuf:"bytes,4,opt,name=cancel,proto3,oneof"`
+}
+
+type Envelope_Stats struct {
+	Stats *Stats `protobuf:"bytes,5,opt,name=stats,proto3,oneof"`
+}
+
+type Envelope_ConfigUpdate struct {
+	ConfigUpdate *ConfigUpdate `protobuf:"bytes,6,opt,name=config_update,json=configUpdate,proto3,oneof"`
+}
+
+type Envelope_Shutdown struct {
+	Shutdown *Shutdown `protobuf:"bytes,7,opt,name=shutdown,proto3,oneof"`
+}
+
+type Envelope_Blocked struct {
+	Blocked *Blocked `protobuf:"bytes,8,opt,name=blocked,proto3,oneof"`
+}
+
+type Envelope_Frame struct {
+	Frame *Frame `protobuf:"bytes,9,opt,name=frame,proto3,oneof"`
+}
+
+func (*Envelope_Request) isEnvelope_Message() {}
+
+func (*Envelope_Response) isEnvelope_Message() {}
+
+func (*Envelope_Error) isEnvelope_Message() {}
+
+func (*Envelope_Cancel) isEnvelope_Message() {}
+
+func (*Envelope_Stats) isEnvelope_Message() {}
+
+func (*Envelope_ConfigUpdate) isEnvelope_Message() {}
+
+func (*Envelope_Shutdown) isEnvelope_Message() {}
+
+func (*Envelope_Blocked) isEnvelope_Message() {}
+
+func (*Envelope_Frame) isEnvelope_Message() {}
+
+// Error reports a failure that isn't the answer to a request, e.g. a message
+// the peer couldn't handle.
+type Error struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The request the error relates to, if any.
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_protos_envelope_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_envelope_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_protos_envelope_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Error) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Cancel tells the peer to stop working on a request.
+type Cancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cancel) Reset() {
+	*x = Cancel{}
+	mi := &file_protos_envelope_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cancel) ProtoMessage() {}
+
+func (x *Cancel) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_envelope_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cancel.ProtoReflect.Descriptor instead.
+func (*Cancel) Descriptor() ([]byte, []int) {
+	return file_protos_envelope_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Cancel) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Cancel) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Stats is sent by the client periodically so the server can tell how busy
+// a tunnel is.
+type Stats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OpenStreams   int64                  `protobuf:"varint,1,opt,name=open_streams,json=openStreams,proto3" json:"open_streams,omitempty"`
+	RequestsTotal int64                  `protobuf:"varint,2,opt,name=requests_total,json=requestsTotal,proto3" json:"requests_total,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_protos_envelope_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_envelope_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_protos_envelope_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Stats) GetOpenStreams() int64 {
+	if x != nil {
+		return x.OpenStreams
+	}
+	return 0
+}
+
+func (x *Stats) GetRequestsTotal() int64 {
+	if x != nil {
+		return x.RequestsTotal
+	}
+	return 0
+}
+
+func (x *Stats) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+// ConfigUpdate changes client settings at runtime. Unset fields are left as
+// they are.
+type ConfigUpdate struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	StatsIntervalMs int64                  `protobuf:"varint,1,opt,name=stats_interval_ms,json=statsIntervalMs,proto3" json:"stats_interval_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ConfigUpdate) Reset() {
+	*x = ConfigUpdate{}
+	mi := &file_protos_envelope_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigUpdate) ProtoMessage() {}
+
+func (x *ConfigUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_envelope_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigUpdate.ProtoReflect.Descriptor instead.
+func (*ConfigUpdate) Descriptor() ([]byte, []int) {
+	return file_protos_envelope_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ConfigUpdate) GetStatsIntervalMs() int64 {
+	if x != nil {
+		return x.StatsIntervalMs
+	}
+	return 0
+}
+
+// Blocked tells the client the server turned a visitor away before
+// forwarding anything, e.g. because of the tunnel's IP filter.
+type Blocked struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	RemoteAddr    string                 `protobuf:"bytes,2,opt,name=remote_addr,json=remoteAddr,proto3" json:"remote_addr,omitempty"`
+	Method        string                 `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	Path          string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Blocked) Reset() {
+	*x = Blocked{}
+	mi := &file_protos_envelope_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Blocked) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Blocked) ProtoMessage() {}
+
+func (x *Blocked) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_envelope_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Blocked.ProtoReflect.Descriptor instead.
+func (*Blocked) Descriptor() ([]byte, []int) {
+	return file_protos_envelope_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Blocked) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Blocked) GetRemoteAddr() string {
+	if x != nil {
+		return x.RemoteAddr
+	}
+	return ""
+}
+
+func (x *Blocked) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *Blocked) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Blocked) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Blocked) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+// Shutdown announces that the sender is going away. A client receiving it
+// should reconnect, a server receiving it can release the app right away.
+type Shutdown struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Shutdown) Reset() {
+	*x = Shutdown{}
+	mi := &file_protos_envelope_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shutdown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shutdown) ProtoMessage() {}
+
+func (x *Shutdown) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_envelope_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shutdown.ProtoReflect.Descriptor instead.
+func (*Shutdown) Descriptor() ([]byte, []int) {
+	return file_protos_envelope_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Shutdown) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+var File_protos_envelope_proto protoreflect.FileDescriptor
+
+const file_protos_envelope_proto_rawDesc = "" +
+	"\n" +
+	"\x15protos/envelope.proto\x12\x06borepb\x1a\x12protos/frame.proto\x1a\x14protos/request.proto\x1a\x15protos/response.proto\"\xab\x03\n" +
+	"\bEnvelope\x12+\n" +
+	"\arequest\x18\x01 \x01(\v2\x0f.borepb.RequestH\x00R\arequest\x12.\n" +
+	"\bresponse\x18\x02 \x01(\v2\x10.borepb.ResponseH\x00R\bresponse\x12%\n" +
+	"\x05error\x18\x03 \x01(\v2\r.borepb.ErrorH\x00R\x05error\x12(\n" +
+	"\x06cancel\x18\x04 \x01(\v2\x0e.borepb.CancelH\x00R\x06cancel\x12%\n" +
+	"\x05stats\x18\x05 \x01(\v2\r.borepb.StatsH\x00R\x05stats\x12;\n" +
+	"\rconfig_update\x18\x06 \x01(\v2\x14.borepb.ConfigUpdateH\x00R\fconfigUpdate\x12.\n" +
+	"\bshutdown\x18\a \x01(\v2\x10.borepb.ShutdownH\x00R\bshutdown\x12+\n" +
+	"\ablocked\x18\b \x01(\v2\x0f.borepb.BlockedH\x00R\ablocked\x12%\n" +
+	"\x05frame\x18\t \x01(\v2\r.borepb.FrameH\x00R\x05frameB\t\n" +
+	"\amessage\"1\n" +
+	"\x05Error\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"0\n" +
+	"\x06Cancel\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"o\n" +
+	"\x05Stats\x12!\n" +
+	"\fopen_streams\x18\x01 \x01(\x03R\vopenStreams\x12%\n" +
+	"\x0erequests_total\x18\x02 \x01(\x03R\rrequestsTotal\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\":\n" +
+	"\fConfigUpdate\x12*\n" +
+	"\x11stats_interval_ms\x18\x01 \x01(\x03R\x0fstatsIntervalMs\"\x9c\x01\n" +
+	"\aBlocked\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vremote_addr\x18\x02 \x01(\tR\n" +
+	"remoteAddr\x12\x16\n" +
+	"\x06method\x18\x03 \x01(\tR\x06method\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\"\"\n" +
+	"\bShutdown\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reasonB\x03Z\x01.b\x06proto3"
+
+var (
+	file_protos_envelope_proto_rawDescOnce sync.Once
+	file_protos_envelope_proto_rawDescData []byte
+)
+
+func file_protos_envelope_proto_rawDescGZIP() []byte {
+	file_protos_envelope_proto_rawDescOnce.Do(func() {
+		file_protos_envelope_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protos_envelope_proto_rawDesc), len(file_protos_envelope_proto_rawDesc)))
+	})
+	return file_protos_envelope_proto_rawDescData
+}
+
+var file_protos_envelope_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_protos_envelope_proto_goTypes = []any{
+	(*Envelope)(nil),     // 0: borepb.Envelope
+	(*Error)(nil),        // 1: borepb.Error
+	(*Cancel)(nil),       // 2: borepb.Cancel
+	(*Stats)(nil),        // 3: borepb.Stats
+	(*ConfigUpdate)(nil), // 4: borepb.ConfigUpdate
+	(*Blocked)(nil),      // 5: borepb.Blocked
+	(*Shutdown)(nil),     // 6: borepb.Shutdown
+	(*Request)(nil),      // 7: borepb.Request
+	(*Response)(nil),     // 8: borepb.Response
+	(*Frame)(nil),        // 9: borepb.Frame
+}
+var file_protos_envelope_proto_depIdxs = []int32{
+	7, // 0: borepb.Envelope.request:type_name -> borepb.Request
+	8, // 1: borepb.Envelope.response:type_name -> borepb.Response
+	1, // 2: borepb.Envelope.error:type_name -> borepb.Error
+	2, // 3: borepb.Envelope.cancel:type_name -> borepb.Cancel
+	3, // 4: borepb.Envelope.stats:type_name -> borepb.Stats
+	4, // 5: borepb.Envelope.config_update:type_name -> borepb.ConfigUpdate
+	6, // 6: borepb.Envelope.shutdown:type_name -> borepb.Shutdown
+	5, // 7: borepb.Envelope.blocked:type_name -> borepb.Blocked
+	9, // 8: borepb.Envelope.frame:type_name -> borepb.Frame
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_protos_envelope_proto_init() }
+func file_protos_envelope_proto_init() {
+	if File_protos_envelope_proto != nil {
+		return
+	}
+	file_protos_frame_proto_init()
+	file_protos_request_proto_init()
+	file_protos_response_proto_init()
+	file_protos_envelope_proto_msgTypes[0].OneofWrappers = []any{
+		(*Envelope_Request)(nil),
+		(*Envelope_Response)(nil),
+		(*Envelope_Error)(nil),
+		(*Envelope_Cancel)(nil),
+		(*Envelope_Stats)(nil),
+		(*Envelope_ConfigUpdate)(nil),
+		(*Envelope_Shutdown)(nil),
+		(*Envelope_Blocked)(nil),
+		(*Envelope_Frame)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_envelope_proto_rawDesc), len(file_protos_envelope_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_protos_envelope_proto_goTypes,
+		DependencyIndexes: file_protos_envelope_proto_depIdxs,
+		MessageInfos:      file_protos_envelope_proto_msgTypes,
+	}.Build()
+	File_protos_envelope_proto = out.File
+	file_protos_envelope_proto_goTypes = nil
+	file_protos_envelope_proto_depIdxs = nil
+}
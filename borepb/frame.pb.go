@@ -0,0 +1,253 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.2
+// source: protos/frame.proto
+
+package __
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FrameType splits a request or response into several websocket messages so
+// bodies can be streamed. FULL and START are Requests or Responses, the rest
+// are Frames on the stream with the request's id.
+type FrameType int32
+
+const (
+	// The whole message, body included, in a single frame.
+	FrameType_FRAME_FULL FrameType = 0
+	// Method/path or status and headers. Body follows in DATA frames.
+	FrameType_FRAME_START FrameType = 1
+	FrameType_FRAME_DATA  FrameType = 2
+	// No more body. An END frame carrying an error aborts the message.
+	// For websocket upgrades, DATA frames carry one websocket message each and
+	// END closes the socket.
+	FrameType_FRAME_END FrameType = 3
+	// Grants the peer window more bytes of DATA on the stream with this id.
+	FrameType_FRAME_WINDOW FrameType = 4
+	// The sender abandoned the stream, so the receiver should stop reading
+	// and writing it.
+	FrameType_FRAME_RESET FrameType = 5
+)
+
+// Enum value maps for FrameType.
+var (
+	FrameType_name = map[int32]string{
+		0: "FRAME_FULL",
+		1: "FRAME_START",
+		2: "FRAME_DATA",
+		3: "FRAME_END",
+		4: "FRAME_WINDOW",
+		5: "FRAME_RESET",
+	}
+	FrameType_value = map[string]int32{
+		"FRAME_FULL":   0,
+		"FRAME_START":  1,
+		"FRAME_DATA":   2,
+		"FRAME_END":    3,
+		"FRAME_WINDOW": 4,
+		"FRAME_RESET":  5,
+	}
+)
+
+func (x FrameType) Enum() *FrameType {
+	p := new(FrameType)
+	*p = x
+	return p
+}
+
+func (x FrameType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FrameType) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_frame_proto_enumTypes[0].Descriptor()
+}
+
+func (FrameType) Type() protoreflect.EnumType {
+	return &file_protos_frame_proto_enumTypes[0]
+}
+
+func (x FrameType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FrameType.Descriptor instead.
+func (FrameType) EnumDescriptor() ([]byte, []int) {
+	return file_protos_frame_proto_rawDescGZIP(), []int{0}
+}
+
+// Frame carries a stream's body and flow control, in either direction.
+type Frame struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	StreamId string                 `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	Type     FrameType              `protobuf:"varint,2,opt,name=type,proto3,enum=borepb.FrameType" json:"type,omitempty"`
+	// The websocket message type of a DATA frame on a websocket upgrade.
+	MessageType int32  `protobuf:"varint,3,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"`
+	Data        []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// Bytes granted by a WINDOW frame.
+	Window uint32 `protobuf:"varint,5,opt,name=window,proto3" json:"window,omitempty"`
+	// Why the sender gave up on the stream, on an END frame.
+	Error         string `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Frame) Reset() {
+	*x = Frame{}
+	mi := &file_protos_frame_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Frame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Frame) ProtoMessage() {}
+
+func (x *Frame) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_frame_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Frame.ProtoReflect.Descriptor instead.
+func (*Frame) Descriptor() ([]byte, []int) {
+	return file_protos_frame_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Frame) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *Frame) GetType() FrameType {
+	if x != nil {
+		return x.Type
+	}
+	return FrameType_FRAME_FULL
+}
+
+func (x *Frame) GetMessageType() int32 {
+	if x != nil {
+		return x.MessageType
+	}
+	return 0
+}
+
+func (x *Frame) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Frame) GetWindow() uint32 {
+	if x != nil {
+		return x.Window
+	}
+	return 0
+}
+
+func (x *Frame) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_protos_frame_proto protoreflect.FileDescriptor
+
+const file_protos_frame_proto_rawDesc = "" +
+	"\n" +
+	"\x12protos/frame.proto\x12\x06borepb\"\xb0\x01\n" +
+	"\x05Frame\x12\x1b\n" +
+	"\tstream_id\x18\x01 \x01(\tR\bstreamId\x12%\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x11.borepb.FrameTypeR\x04type\x12!\n" +
+	"\fmessage_type\x18\x03 \x01(\x05R\vmessageType\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x16\n" +
+	"\x06window\x18\x05 \x01(\rR\x06window\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error*n\n" +
+	"\tFrameType\x12\x0e\n" +
+	"\n" +
+	"FRAME_FULL\x10\x00\x12\x0f\n" +
+	"\vFRAME_START\x10\x01\x12\x0e\n" +
+	"\n" +
+	"FRAME_DATA\x10\x02\x12\r\n" +
+	"\tFRAME_END\x10\x03\x12\x10\n" +
+	"\fFRAME_WINDOW\x10\x04\x12\x0f\n" +
+	"\vFRAME_RESET\x10\x05B\x03Z\x01.b\x06proto3"
+
+var (
+	file_protos_frame_proto_rawDescOnce sync.Once
+	file_protos_frame_proto_rawDescData []byte
+)
+
+func file_protos_frame_proto_rawDescGZIP() []byte {
+	file_protos_frame_proto_rawDescOnce.Do(func() {
+		file_protos_frame_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protos_frame_proto_rawDesc), len(file_protos_frame_proto_rawDesc)))
+	})
+	return file_protos_frame_proto_rawDescData
+}
+
+var file_protos_frame_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_protos_frame_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_protos_frame_proto_goTypes = []any{
+	(FrameType)(0), // 0: borepb.FrameType
+	(*Frame)(nil),  // 1: borepb.Frame
+}
+var file_protos_frame_proto_depIdxs = []int32{
+	0, // 0: borepb.Frame.type:type_name -> borepb.FrameType
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_protos_frame_proto_init() }
+func file_protos_frame_proto_init() {
+	if File_protos_frame_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_frame_proto_rawDesc), len(file_protos_frame_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_protos_frame_proto_goTypes,
+		DependencyIndexes: file_protos_frame_proto_depIdxs,
+		EnumInfos:         file_protos_frame_proto_enumTypes,
+		MessageInfos:      file_protos_frame_proto_msgTypes,
+	}.Build()
+	File_protos_frame_proto = out.File
+	file_protos_frame_proto_goTypes = nil
+	file_protos_frame_proto_depIdxs = nil
+}
@@ -0,0 +1,366 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.2
+// source: protos/handshake.proto
+
+package __
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Hello is the first message a bore client sends after the websocket is
+// upgraded.
+type Hello struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ProtocolVersion uint32                 `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	ClientVersion   string                 `protobuf:"bytes,2,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	// Features the client wants to use, e.g. "tcp" for a TCP tunnel.
+	Features []string `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"`
+	// Set when reconnecting, to keep the app ID of the previous connection.
+	ResumeToken string `protobuf:"bytes,4,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	// Subdomain the client asks for instead of a random app ID.
+	Subdomain string `protobuf:"bytes,5,opt,name=subdomain,proto3" json:"subdomain,omitempty"`
+	// API token, required by servers that have tokens configured.
+	Token string `protobuf:"bytes,6,opt,name=token,proto3" json:"token,omitempty"`
+	// Credentials visitors must present before requests are forwarded, as
+	// "user:pass" for basic auth and a bearer token respectively.
+	BasicAuth   string `protobuf:"bytes,7,opt,name=basic_auth,json=basicAuth,proto3" json:"basic_auth,omitempty"`
+	BearerToken string `protobuf:"bytes,8,opt,name=bearer_token,json=bearerToken,proto3" json:"bearer_token,omitempty"`
+	// CIDR ranges visitors must come from, and ranges they must not come
+	// from. Deny wins over allow.
+	AllowCidrs []string `protobuf:"bytes,9,rep,name=allow_cidrs,json=allowCidrs,proto3" json:"allow_cidrs,omitempty"`
+	DenyCidrs  []string `protobuf:"bytes,10,rep,name=deny_cidrs,json=denyCidrs,proto3" json:"deny_cidrs,omitempty"`
+	// Hostname the client wants its tunnel served on, e.g. a domain CNAMEd
+	// to the bore server. The server verifies ownership before binding it.
+	CustomDomain string `protobuf:"bytes,11,opt,name=custom_domain,json=customDomain,proto3" json:"custom_domain,omitempty"`
+	// Secret kept by the client that the custom domain's TXT record is
+	// derived from, so only its holder can claim the domain.
+	DomainKey     string `protobuf:"bytes,12,opt,name=domain_key,json=domainKey,proto3" json:"domain_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	mi := &file_protos_handshake_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_handshake_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_protos_handshake_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Hello) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *Hello) GetClientVersion() string {
+	if x != nil {
+		return x.ClientVersion
+	}
+	return ""
+}
+
+func (x *Hello) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *Hello) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *Hello) GetSubdomain() string {
+	if x != nil {
+		return x.Subdomain
+	}
+	return ""
+}
+
+func (x *Hello) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *Hello) GetBasicAuth() string {
+	if x != nil {
+		return x.BasicAuth
+	}
+	return ""
+}
+
+func (x *Hello) GetBearerToken() string {
+	if x != nil {
+		return x.BearerToken
+	}
+	return ""
+}
+
+func (x *Hello) GetAllowCidrs() []string {
+	if x != nil {
+		return x.AllowCidrs
+	}
+	return nil
+}
+
+func (x *Hello) GetDenyCidrs() []string {
+	if x != nil {
+		return x.DenyCidrs
+	}
+	return nil
+}
+
+func (x *Hello) GetCustomDomain() string {
+	if x != nil {
+		return x.CustomDomain
+	}
+	return ""
+}
+
+func (x *Hello) GetDomainKey() string {
+	if x != nil {
+		return x.DomainKey
+	}
+	return ""
+}
+
+// Welcome is the server's answer to Hello. When error is set the server
+// closes the connection, and the client should not retry.
+type Welcome struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ProtocolVersion uint32                 `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	ServerVersion   string                 `protobuf:"bytes,2,opt,name=server_version,json=serverVersion,proto3" json:"server_version,omitempty"`
+	Capabilities    []string               `protobuf:"bytes,3,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	AppId           string                 `protobuf:"bytes,4,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	ResumeToken     string                 `protobuf:"bytes,5,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	TcpPort         int32                  `protobuf:"varint,6,opt,name=tcp_port,json=tcpPort,proto3" json:"tcp_port,omitempty"`
+	Error           string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	// The custom domain bound to the app, if the client asked for one.
+	CustomDomain string `protobuf:"bytes,8,opt,name=custom_domain,json=customDomain,proto3" json:"custom_domain,omitempty"`
+	// The URL visitors reach the app on: its custom domain, or a subdomain of
+	// the server's configured domains, or of the host the client connected
+	// to if the server has none configured. Older servers leave it empty.
+	PublicUrl     string `protobuf:"bytes,9,opt,name=public_url,json=publicUrl,proto3" json:"public_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Welcome) Reset() {
+	*x = Welcome{}
+	mi := &file_protos_handshake_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Welcome) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Welcome) ProtoMessage() {}
+
+func (x *Welcome) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_handshake_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Welcome.ProtoReflect.Descriptor instead.
+func (*Welcome) Descriptor() ([]byte, []int) {
+	return file_protos_handshake_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Welcome) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *Welcome) GetServerVersion() string {
+	if x != nil {
+		return x.ServerVersion
+	}
+	return ""
+}
+
+func (x *Welcome) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *Welcome) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *Welcome) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *Welcome) GetTcpPort() int32 {
+	if x != nil {
+		return x.TcpPort
+	}
+	return 0
+}
+
+func (x *Welcome) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Welcome) GetCustomDomain() string {
+	if x != nil {
+		return x.CustomDomain
+	}
+	return ""
+}
+
+func (x *Welcome) GetPublicUrl() string {
+	if x != nil {
+		return x.PublicUrl
+	}
+	return ""
+}
+
+var File_protos_handshake_proto protoreflect.FileDescriptor
+
+const file_protos_handshake_proto_rawDesc = "" +
+	"\n" +
+	"\x16protos/handshake.proto\x12\x06borepb\"\x92\x03\n" +
+	"\x05Hello\x12)\n" +
+	"\x10protocol_version\x18\x01 \x01(\rR\x0fprotocolVersion\x12%\n" +
+	"\x0eclient_version\x18\x02 \x01(\tR\rclientVersion\x12\x1a\n" +
+	"\bfeatures\x18\x03 \x03(\tR\bfeatures\x12!\n" +
+	"\fresume_token\x18\x04 \x01(\tR\vresumeToken\x12\x1c\n" +
+	"\tsubdomain\x18\x05 \x01(\tR\tsubdomain\x12\x14\n" +
+	"\x05token\x18\x06 \x01(\tR\x05token\x12\x1d\n" +
+	"\n" +
+	"basic_auth\x18\a \x01(\tR\tbasicAuth\x12!\n" +
+	"\fbearer_token\x18\b \x01(\tR\vbearerToken\x12\x1f\n" +
+	"\vallow_cidrs\x18\t \x03(\tR\n" +
+	"allowCidrs\x12\x1d\n" +
+	"\n" +
+	"deny_cidrs\x18\n" +
+	" \x03(\tR\tdenyCidrs\x12#\n" +
+	"\rcustom_domain\x18\v \x01(\tR\fcustomDomain\x12\x1d\n" +
+	"\n" +
+	"domain_key\x18\f \x01(\tR\tdomainKey\"\xae\x02\n" +
+	"\aWelcome\x12)\n" +
+	"\x10protocol_version\x18\x01 \x01(\rR\x0fprotocolVersion\x12%\n" +
+	"\x0eserver_version\x18\x02 \x01(\tR\rserverVersion\x12\"\n" +
+	"\fcapabilities\x18\x03 \x03(\tR\fcapabilities\x12\x15\n" +
+	"\x06app_id\x18\x04 \x01(\tR\x05appId\x12!\n" +
+	"\fresume_token\x18\x05 \x01(\tR\vresumeToken\x12\x19\n" +
+	"\btcp_port\x18\x06 \x01(\x05R\atcpPort\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12#\n" +
+	"\rcustom_domain\x18\b \x01(\tR\fcustomDomain\x12\x1d\n" +
+	"\n" +
+	"public_url\x18\t \x01(\tR\tpublicUrlB\x03Z\x01.b\x06proto3"
+
+var (
+	file_protos_handshake_proto_rawDescOnce sync.Once
+	file_protos_handshake_proto_rawDescData []byte
+)
+
+func file_protos_handshake_proto_rawDescGZIP() []byte {
+	file_protos_handshake_proto_rawDescOnce.Do(func() {
+		file_protos_handshake_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protos_handshake_proto_rawDesc), len(file_protos_handshake_proto_rawDesc)))
+	})
+	return file_protos_handshake_proto_rawDescData
+}
+
+var file_protos_handshake_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_protos_handshake_proto_goTypes = []any{
+	(*Hello)(nil),   // 0: borepb.Hello
+	(*Welcome)(nil), // 1: borepb.Welcome
+}
+var file_protos_handshake_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_protos_handshake_proto_init() }
+func file_protos_handshake_proto_init() {
+	if File_protos_handshake_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_handshake_proto_rawDesc), len(file_protos_handshake_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_protos_handshake_proto_goTypes,
+		DependencyIndexes: file_protos_handshake_proto_depIdxs,
+		MessageInfos:      file_protos_handshake_proto_msgTypes,
+	}.Build()
+	File_protos_handshake_proto = out.File
+	file_protos_handshake_proto_goTypes = nil
+	file_protos_handshake_proto_depIdxs = nil
+}
@@ -30,6 +30,8 @@ type Request struct {
 	Body          []byte                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
 	Timestamp     int64                  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Cookies       string                 `protobuf:"bytes,7,opt,name=cookies,proto3" json:"cookies,omitempty"`
+	Frame         FrameType              `protobuf:"varint,8,opt,name=frame,proto3,enum=borepb.FrameType" json:"frame,omitempty"`
+	Upgrade       bool                   `protobuf:"varint,9,opt,name=upgrade,proto3" json:"upgrade,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -113,11 +115,25 @@ func (x *Request) GetCookies() string {
 	return ""
 }
 
+func (x *Request) GetFrame() FrameType {
+	if x != nil {
+		return x.Frame
+	}
+	return FrameType_FRAME_FULL
+}
+
+func (x *Request) GetUpgrade() bool {
+	if x != nil {
+		return x.Upgrade
+	}
+	return false
+}
+
 var File_protos_request_proto protoreflect.FileDescriptor
 
 const file_protos_request_proto_rawDesc = "" +
 	"\n" +
-	"\x14protos/request.proto\x12\x06borepb\"\x85\x02\n" +
+	"\x14protos/request.proto\x12\x06borepb\x1a\x12protos/frame.proto\"\xd4\x02\n" +
 	"\aRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
 	"\x06method\x18\x02 \x01(\tR\x06method\x12\x12\n" +
@@ -125,10 +141,13 @@ const file_protos_request_proto_rawDesc = "" +
 	"\aheaders\x18\x04 \x03(\v2\x1c.borepb.Request.HeadersEntryR\aheaders\x12\x12\n" +
 	"\x04body\x18\x05 \x01(\fR\x04body\x12\x1c\n" +
 	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\x12\x18\n" +
-	"\acookies\x18\a \x01(\tR\acookies\x1a:\n" +
+	"\acookies\x18\a \x01(\tR\acookies\x12'\n" +
+	"\x05frame\x18\b \x01(\x0e2\x11.borepb.FrameTypeR\x05frame\x12\x18\n" +
+	"\aupgrade\x18\t \x01(\bR\aupgrade\x1a:\n" +
 	"\fHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x03Z\x01.b\x06proto3"
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01J\x04\b\n" +
+	"\x10\vJ\x04\b\v\x10\fB\x03Z\x01.b\x06proto3"
 
 var (
 	file_protos_request_proto_rawDescOnce sync.Once
@@ -146,14 +165,16 @@ var file_protos_request_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_protos_request_proto_goTypes = []any{
 	(*Request)(nil), // 0: borepb.Request
 	nil,             // 1: borepb.Request.HeadersEntry
+	(FrameType)(0),  // 2: borepb.FrameType
 }
 var file_protos_request_proto_depIdxs = []int32{
 	1, // 0: borepb.Request.headers:type_name -> borepb.Request.HeadersEntry
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	2, // 1: borepb.Request.frame:type_name -> borepb.FrameType
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_protos_request_proto_init() }
@@ -161,6 +182,7 @@ func file_protos_request_proto_init() {
 	if File_protos_request_proto != nil {
 		return
 	}
+	file_protos_frame_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
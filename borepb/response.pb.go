@@ -29,6 +29,8 @@ type Response struct {
 	Body          []byte                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
 	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Cookies       string                 `protobuf:"bytes,6,opt,name=cookies,proto3" json:"cookies,omitempty"`
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	Frame         FrameType              `protobuf:"varint,8,opt,name=frame,proto3,enum=borepb.FrameType" json:"frame,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -105,11 +107,25 @@ func (x *Response) GetCookies() string {
 	return ""
 }
 
+func (x *Response) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Response) GetFrame() FrameType {
+	if x != nil {
+		return x.Frame
+	}
+	return FrameType_FRAME_FULL
+}
+
 var File_protos_response_proto protoreflect.FileDescriptor
 
 const file_protos_response_proto_rawDesc = "" +
 	"\n" +
-	"\x15protos/response.proto\x12\x06borepb\"\xfc\x01\n" +
+	"\x15protos/response.proto\x12\x06borepb\x1a\x12protos/frame.proto\"\xc7\x02\n" +
 	"\bResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
 	"\vstatus_code\x18\x02 \x01(\x05R\n" +
@@ -117,10 +133,14 @@ const file_protos_response_proto_rawDesc = "" +
 	"\aheaders\x18\x03 \x03(\v2\x1d.borepb.Response.HeadersEntryR\aheaders\x12\x12\n" +
 	"\x04body\x18\x04 \x01(\fR\x04body\x12\x1c\n" +
 	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\x12\x18\n" +
-	"\acookies\x18\x06 \x01(\tR\acookies\x1a:\n" +
+	"\acookies\x18\x06 \x01(\tR\acookies\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12'\n" +
+	"\x05frame\x18\b \x01(\x0e2\x11.borepb.FrameTypeR\x05frame\x1a:\n" +
 	"\fHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x03Z\x01.b\x06proto3"
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01J\x04\b\t\x10\n" +
+	"J\x04\b\n" +
+	"\x10\vB\x03Z\x01.b\x06proto3"
 
 var (
 	file_protos_response_proto_rawDescOnce sync.Once
@@ -138,14 +158,16 @@ var file_protos_response_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_protos_response_proto_goTypes = []any{
 	(*Response)(nil), // 0: borepb.Response
 	nil,              // 1: borepb.Response.HeadersEntry
+	(FrameType)(0),   // 2: borepb.FrameType
 }
 var file_protos_response_proto_depIdxs = []int32{
 	1, // 0: borepb.Response.headers:type_name -> borepb.Response.HeadersEntry
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	2, // 1: borepb.Response.frame:type_name -> borepb.FrameType
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_protos_response_proto_init() }
@@ -153,6 +175,7 @@ func file_protos_response_proto_init() {
 	if File_protos_response_proto != nil {
 		return
 	}
+	file_protos_frame_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
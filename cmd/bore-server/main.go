@@ -2,16 +2,51 @@ package main
 
 import (
 	"bore/internal/server"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
 )
 
 var AppVersion string
 
 type Flags struct {
-	Version bool
-	Port    int
-	LogFile string
+	Version            bool
+	Port               int
+	LogFile            string
+	ResumeGracePeriod  time.Duration
+	RequestTimeout     time.Duration
+	TCPPortMin         int
+	TCPPortMax         int
+	ReservedSubdomains []string
+	Tokens             []server.TokenConfig
+	RateLimit          float64
+	RateBurst          int
+	VisitorRateLimit   float64
+	VisitorRateBurst   int
+	MaxBodySize        int64
+	MaxInFlight        int
+	AdminAddr          string
+	AdminToken         string
+	DrainTimeout       time.Duration
+	TLSCert            string
+	TLSKey             string
+	ACME               bool
+	ACMEEmail          string
+	ACMEDirectory      string
+	ACMECacheDir       string
+	HTTPSPort          int
+	HTTPPort           int
+	Domains            []string
+	Host               string
+	Scheme             string
+	TrustedProxies     []netip.Prefix
 }
 
 func ParseFlags() Flags {
@@ -24,16 +59,208 @@ func ParseFlags() Flags {
 	logFile := flag.String("log-file", "./logs/bore.log", "Log file path")
 	flag.StringVar(logFile, "l", "./logs/bore.log", "Log file path")
 
+	resumeGracePeriod := flag.Duration("resume-grace", 2*time.Minute, "How long a disconnected app's ID is kept for the client to resume")
+	requestTimeout := flag.Duration("request-timeout", 60*time.Second, "How long to wait for the bore client to respond to a request")
+	drainTimeout := flag.Duration("drain-timeout", 30*time.Second, "How long in-flight requests get to finish when the server is stopped")
+	tcpPorts := flag.String("tcp-ports", "", "Range of public ports to allocate to TCP tunnels, e.g. 20000-20999 (disabled by default)")
+	tokensFile := flag.String("tokens", "", "Path to a JSON file of API tokens clients must present (anyone can connect by default)")
+	domains := flag.String("domains", "", "Comma-separated public domains apps are served under, e.g. tunnels.example.co.uk, with a port if not the default (defaults to the first label of the host being the app ID)")
+	host := flag.String("host", "", "Host bore clients connect to, e.g. app.trybore.com, so tunneled apps can use /ws on their own hosts (by default /ws is always for bore clients)")
+	scheme := flag.String("scheme", "https", "Scheme of the public app URLs, https or http")
+	trustedProxies := flag.String("trusted-proxy", server.DefaultTrustedProxies, "Comma-separated CIDR ranges of proxies whose X-Real-IP header is trusted, e.g. nginx")
+	reservedSubdomains := flag.String("reserved-subdomains", "", "Comma-separated subdomains clients can't claim, in addition to www, api, admin, app, ws, mail, status and docs")
+
+	rateLimit := flag.Float64("rate-limit", 0, "Requests per second allowed on each tunnel (unlimited by default)")
+	rateBurst := flag.Int("rate-burst", 0, "Requests a tunnel can burst above --rate-limit (defaults to the rate)")
+	visitorRateLimit := flag.Float64("visitor-rate-limit", 0, "Requests per second allowed from each visitor IP (unlimited by default)")
+	visitorRateBurst := flag.Int("visitor-rate-burst", 0, "Requests a visitor can burst above --visitor-rate-limit (defaults to the rate)")
+	maxBodySize := flag.String("max-body-size", "", "Largest request body forwarded, e.g. 10MB (unlimited by default)")
+	adminAddr := flag.String("admin-addr", "", "Address to serve /metrics and the admin API on, e.g. 127.0.0.1:9100 (disabled by default)")
+	maxInFlight := flag.Int("max-in-flight", 0, "Requests each tunnel can have in flight at once (unlimited by default)")
+
+	tlsCert := flag.String("tls-cert", "", "Wildcard certificate for --domains, to terminate TLS without nginx (off by default)")
+	tlsKey := flag.String("tls-key", "", "Private key of --tls-cert")
+	useACME := flag.Bool("acme", false, "Get certificates from ACME, to terminate TLS without nginx: for verified custom domains, and for every host when there is no --tls-cert")
+	acmeEmail := flag.String("acme-email", "", "Contact email for the ACME account")
+	acmeDirectory := flag.String("acme-directory", acme.LetsEncryptURL, "ACME directory URL, e.g. a local Pebble instance for testing")
+	acmeCacheDir := flag.String("acme-cache", "./certs", "Directory to keep ACME account keys and certificates in")
+	httpsPort := flag.Int("https-port", 443, "Port to serve HTTPS on when --tls-cert or --acme is set")
+	httpPort := flag.Int("http-port", 80, "Port to serve HTTP to HTTPS redirects and ACME challenges on when --tls-cert or --acme is set")
+
 	flag.Parse()
 
+	var maxBodyBytes int64
+	if *maxBodySize != "" {
+		var err error
+		maxBodyBytes, err = parseByteSize(*maxBodySize)
+		if err != nil {
+			fmt.Println("Invalid --max-body-size:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *scheme != "https" && *scheme != "http" {
+		fmt.Println("Invalid --scheme: must be https or http")
+		os.Exit(1)
+	}
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Println("Invalid --tls-cert: --tls-cert and --tls-key must be set together")
+		os.Exit(1)
+	}
+
+	if *tlsCert != "" && *domains == "" {
+		fmt.Println("Invalid --tls-cert: set the domains it is for with --domains")
+		os.Exit(1)
+	}
+
+	if *useACME && *domains == "" {
+		fmt.Println("Invalid --acme: set the domains to get certificates for with --domains")
+		os.Exit(1)
+	}
+
+	proxies, err := server.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		fmt.Println("Invalid --trusted-proxy:", err)
+		os.Exit(1)
+	}
+
+	var tcpPortMin, tcpPortMax int
+	if *tcpPorts != "" {
+		var err error
+		tcpPortMin, tcpPortMax, err = parsePortRange(*tcpPorts)
+		if err != nil {
+			fmt.Println("Invalid --tcp-ports:", err)
+			os.Exit(1)
+		}
+	}
+
+	var tokens []server.TokenConfig
+	if *tokensFile != "" {
+		var err error
+		tokens, err = server.LoadTokens(*tokensFile)
+		if err != nil {
+			fmt.Println("Invalid --tokens:", err)
+			os.Exit(1)
+		}
+	}
+
 	return Flags{
-		Version: *version,
-		Port:    *port,
-		LogFile: *logFile,
+		Version:            *version,
+		Port:               *port,
+		LogFile:            *logFile,
+		ResumeGracePeriod:  *resumeGracePeriod,
+		RequestTimeout:     *requestTimeout,
+		TCPPortMin:         tcpPortMin,
+		TCPPortMax:         tcpPortMax,
+		ReservedSubdomains: strings.Split(*reservedSubdomains, ","),
+		Tokens:             tokens,
+		RateLimit:          *rateLimit,
+		RateBurst:          *rateBurst,
+		VisitorRateLimit:   *visitorRateLimit,
+		VisitorRateBurst:   *visitorRateBurst,
+		MaxBodySize:        maxBodyBytes,
+		MaxInFlight:        *maxInFlight,
+		AdminAddr:          *adminAddr,
+		AdminToken:         os.Getenv("BORE_ADMIN_TOKEN"),
+		DrainTimeout:       *drainTimeout,
+		TLSCert:            *tlsCert,
+		TLSKey:             *tlsKey,
+		ACME:               *useACME,
+		ACMEEmail:          *acmeEmail,
+		ACMEDirectory:      *acmeDirectory,
+		ACMECacheDir:       *acmeCacheDir,
+		HTTPSPort:          *httpsPort,
+		HTTPPort:           *httpPort,
+		Domains:            strings.Split(*domains, ","),
+		Host:               *host,
+		Scheme:             *scheme,
+		TrustedProxies:     proxies,
+	}
+}
+
+// parseByteSize parses sizes like 512, 64KB, 10MB or 1GB.
+func parseByteSize(size string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	upper := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper = strings.TrimSuffix(upper, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
 	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a valid size", size)
+	}
+
+	return n * multiplier, nil
+}
+
+func parsePortRange(portRange string) (int, int, error) {
+	minPort, maxPort, found := strings.Cut(portRange, "-")
+	if !found {
+		maxPort = minPort
+	}
+
+	low, err := strconv.Atoi(minPort)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	high, err := strconv.Atoi(maxPort)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("%q is not a valid port range", portRange)
+	}
+
+	return low, high, nil
+}
+
+// generateToken prints a new API token along with the entry to add to the
+// tokens file, e.g. `bore-server token ci`.
+func generateToken(args []string) {
+	name := "default"
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	token, hash, err := server.GenerateToken()
+	if err != nil {
+		fmt.Println("Failed to generate token:", err)
+		os.Exit(1)
+	}
+
+	entry, _ := json.MarshalIndent(server.TokenConfig{Name: name, SHA256: hash, Subdomains: []string{}}, "", "  ")
+
+	fmt.Println("Token (shown only once, give it to the client for `bore login`):")
+	fmt.Println(token)
+	fmt.Println()
+	fmt.Println("Add this entry to the tokens file:")
+	fmt.Println(string(entry))
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		generateToken(os.Args[2:])
+		return
+	}
+
 	flags := ParseFlags()
 
 	if flags.Version {
@@ -42,9 +269,36 @@ func main() {
 	}
 
 	bs := server.NewBoreServer(&server.BoreServerCfg{
-		Port:    flags.Port,
-		LogFile: flags.LogFile,
-		Version: AppVersion,
+		Port:               flags.Port,
+		LogFile:            flags.LogFile,
+		Version:            AppVersion,
+		ResumeGracePeriod:  flags.ResumeGracePeriod,
+		RequestTimeout:     flags.RequestTimeout,
+		TCPPortMin:         flags.TCPPortMin,
+		TCPPortMax:         flags.TCPPortMax,
+		ReservedSubdomains: flags.ReservedSubdomains,
+		Tokens:             flags.Tokens,
+		RateLimit:          flags.RateLimit,
+		RateBurst:          flags.RateBurst,
+		VisitorRateLimit:   flags.VisitorRateLimit,
+		VisitorRateBurst:   flags.VisitorRateBurst,
+		MaxBodySize:        flags.MaxBodySize,
+		MaxInFlight:        flags.MaxInFlight,
+		AdminAddr:          flags.AdminAddr,
+		AdminToken:         flags.AdminToken,
+		DrainTimeout:       flags.DrainTimeout,
+		TLSCert:            flags.TLSCert,
+		TLSKey:             flags.TLSKey,
+		ACME:               flags.ACME,
+		ACMEEmail:          flags.ACMEEmail,
+		ACMEDirectory:      flags.ACMEDirectory,
+		ACMECacheDir:       flags.ACMECacheDir,
+		HTTPSPort:          flags.HTTPSPort,
+		HTTPPort:           flags.HTTPPort,
+		Domains:            flags.Domains,
+		Host:               flags.Host,
+		Scheme:             flags.Scheme,
+		TrustedProxies:     flags.TrustedProxies,
 	})
 
 	err := bs.StartBoreServer()
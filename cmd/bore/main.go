@@ -5,16 +5,47 @@ import (
 	"bore/internal/traffik"
 	"bore/internal/ui/tui"
 	"bore/internal/ui/web"
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 var AppVersion string
 
+// how long in-flight requests get to finish when bore is quit
+const shutdownTimeout = 10 * time.Second
+
+// cidrList collects a flag that can be repeated or given comma-separated
+// values, e.g. --allow-cidr 10.0.0.0/8,192.168.0.0/16.
+type cidrList []string
+
+func (l *cidrList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *cidrList) Set(value string) error {
+	for cidr := range strings.SplitSeq(value, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			*l = append(*l, cidr)
+		}
+	}
+
+	return nil
+}
+
 type Flags struct {
 	UpstreamURL   string
 	Inspect       bool
@@ -22,6 +53,14 @@ type Flags struct {
 	InspectPort   int
 	allowExternal bool
 	NoTui         bool
+	Concurrency   int
+	SaveTraffic   string
+	Subdomain     string
+	CustomDomain  string
+	BasicAuth     string
+	BearerToken   string
+	AllowCIDRs    []string
+	DenyCIDRs     []string
 }
 
 func ParseFlags() Flags {
@@ -39,6 +78,23 @@ func ParseFlags() Flags {
 	allowExternal := flag.Bool("allow-external", false, "Allow proxying non-localhost targets (disabled by default)")
 	noTui := flag.Bool("no-tui", false, "Disable the terminal user interface")
 
+	concurrency := flag.Int("concurrency", 32, "Maximum number of requests proxied to the upstream concurrently")
+	flag.IntVar(concurrency, "c", 32, "Maximum number of requests proxied to the upstream concurrently")
+
+	saveTraffic := flag.String("save-traffic", "", "Save the captured traffic logs to this JSON file on exit")
+
+	subdomain := flag.String("subdomain", "", "Subdomain to request instead of a random one, e.g. myteam-api")
+	flag.StringVar(subdomain, "s", "", "Subdomain to request instead of a random one, e.g. myteam-api")
+
+	customDomain := flag.String("domain", "", "Custom domain to serve the tunnel on, e.g. staging.example.com CNAMEd to the bore server")
+
+	basicAuth := flag.String("auth", "", "Require visitors to sign in with HTTP basic auth, as user:pass")
+	bearerToken := flag.String("auth-token", "", "Require visitors to send this token as an Authorization: Bearer header")
+
+	var allowCIDRs, denyCIDRs cidrList
+	flag.Var(&allowCIDRs, "allow-cidr", "Only let visitors from this CIDR range through, e.g. 10.0.0.0/8 (repeatable)")
+	flag.Var(&denyCIDRs, "deny-cidr", "Turn away visitors from this CIDR range (repeatable)")
+
 	flag.Parse()
 
 	if *version {
@@ -51,6 +107,11 @@ func ParseFlags() Flags {
 		os.Exit(1)
 	}
 
+	if *basicAuth != "" && !strings.Contains(*basicAuth, ":") {
+		fmt.Println("Invalid --auth. Use --auth user:pass.")
+		os.Exit(1)
+	}
+
 	return Flags{
 		UpstreamURL:   *upstreamURL,
 		InspectPort:   *inspectPort,
@@ -58,10 +119,205 @@ func ParseFlags() Flags {
 		allowExternal: *allowExternal,
 		Debug:         *debug,
 		NoTui:         *noTui,
+		Concurrency:   *concurrency,
+		SaveTraffic:   *saveTraffic,
+		Subdomain:     *subdomain,
+		CustomDomain:  *customDomain,
+		BasicAuth:     *basicAuth,
+		BearerToken:   *bearerToken,
+		AllowCIDRs:    allowCIDRs,
+		DenyCIDRs:     denyCIDRs,
+	}
+}
+
+type TCPFlags struct {
+	Port          int
+	Host          string
+	Debug         bool
+	allowExternal bool
+	AllowCIDRs    []string
+	DenyCIDRs     []string
+}
+
+func ParseTCPFlags(args []string) TCPFlags {
+	tcpFlags := flag.NewFlagSet("tcp", flag.ExitOnError)
+
+	port := tcpFlags.Int("port", 0, "Local port to expose over TCP")
+	tcpFlags.IntVar(port, "p", 0, "Local port to expose over TCP")
+
+	host := tcpFlags.String("host", "localhost", "Host the local service listens on")
+
+	debug := tcpFlags.Bool("debug", false, "Enable debug mode (logs internal bore logs to a file)")
+	tcpFlags.BoolVar(debug, "d", false, "Enable debug mode (logs internal bore logs to a file)")
+
+	allowExternal := tcpFlags.Bool("allow-external", false, "Allow proxying non-localhost targets (disabled by default)")
+
+	var allowCIDRs, denyCIDRs cidrList
+	tcpFlags.Var(&allowCIDRs, "allow-cidr", "Only let visitors from this CIDR range through, e.g. 10.0.0.0/8 (repeatable)")
+	tcpFlags.Var(&denyCIDRs, "deny-cidr", "Turn away visitors from this CIDR range (repeatable)")
+
+	tcpFlags.Parse(args)
+
+	if *port == 0 {
+		fmt.Println("Port is required. Use -port or -p to specify it.")
+		os.Exit(1)
+	}
+
+	return TCPFlags{
+		Port:          *port,
+		Host:          *host,
+		Debug:         *debug,
+		allowExternal: *allowExternal,
+		AllowCIDRs:    allowCIDRs,
+		DenyCIDRs:     denyCIDRs,
+	}
+}
+
+// loadToken returns the token stored by `bore login`, if any.
+func loadToken() string {
+	token, err := client.LoadToken()
+	if err != nil {
+		fmt.Printf("Failed to read bore token: %v\n", err)
+		os.Exit(1)
+	}
+
+	return token
+}
+
+// loadDomainKey returns the key custom domains are verified with, or "" if
+// no custom domain was asked for.
+func loadDomainKey(customDomain string) string {
+	if customDomain == "" {
+		return ""
+	}
+
+	key, err := client.LoadDomainKey()
+	if err != nil {
+		fmt.Printf("Failed to read bore domain key: %v\n", err)
+		os.Exit(1)
+	}
+
+	return key
+}
+
+// runLogin stores a token for servers that require one, e.g.
+// `bore login bore_...`. The token is read from stdin when not given.
+func runLogin(args []string) {
+	var token string
+	if len(args) > 0 {
+		token = args[0]
+	} else {
+		fmt.Print("Token: ")
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		token = scanner.Text()
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		fmt.Println("Token is required. Usage: bore login <token>")
+		os.Exit(1)
+	}
+
+	path, err := client.SaveToken(token)
+	if err != nil {
+		fmt.Printf("Failed to store bore token: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Token stored in", path)
+}
+
+// runTCP exposes a local TCP service, e.g. `bore tcp --port 5432`.
+func runTCP(args []string) {
+	flags := ParseTCPFlags(args)
+	upstreamAddr := net.JoinHostPort(flags.Host, strconv.Itoa(flags.Port))
+
+	bc := client.NewBoreClient(&client.BoreClientConfig{
+		UpstreamURL:   "tcp://" + upstreamAddr,
+		Traffik:       traffik.NewLogger(),
+		AllowExternal: flags.allowExternal,
+		DebugMode:     flags.Debug,
+		Version:       AppVersion,
+		NoTui:         true,
+		Token:         loadToken(),
+		AllowCIDRs:    flags.AllowCIDRs,
+		DenyCIDRs:     flags.DenyCIDRs,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-bc.Ready
+		fmt.Printf("Forwarding %s -> %s\n", bc.AppURL(), upstreamAddr)
+
+		for appURL := range bc.URLChanged {
+			fmt.Printf("Forwarding %s -> %s\n", appURL, upstreamAddr)
+		}
+	}()
+
+	registered := make(chan error, 1)
+	go func() {
+		registered <- bc.RegisterApp()
+	}()
+
+	select {
+	case err := <-registered:
+		fmt.Printf("Failed to start bore client: %v\n", err)
+		os.Exit(1)
+	case <-ctx.Done():
+		stop()
+		shutdown(bc, nil, nil, "")
+		<-registered
+	}
+}
+
+// shutdown lets in-flight requests finish and closes the tunnel and the web
+// inspector, giving up after shutdownTimeout. A second Ctrl+C exits right
+// away. Traffic logs are saved to saveTraffic when it is set.
+func shutdown(bc *client.BoreClient, ws *web.WebServer, traffik *traffik.Logger, saveTraffic string) {
+	fmt.Println("Shutting down bore, press Ctrl+C again to force quit...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := bc.Shutdown(ctx)
+	if err != nil {
+		fmt.Printf("Failed to close tunnel: %v\n", err)
+	}
+
+	if ws != nil {
+		err = ws.Shutdown(ctx)
+		if err != nil {
+			fmt.Printf("Failed to stop web inspector: %v\n", err)
+		}
+	}
+
+	if saveTraffic != "" {
+		err = traffik.Save(saveTraffic)
+		if err != nil {
+			fmt.Printf("Failed to save traffic logs: %v\n", err)
+		} else {
+			fmt.Println("Traffic logs saved to", saveTraffic)
+		}
 	}
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "tcp" {
+		runTCP(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		runLogin(os.Args[2:])
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
@@ -75,6 +331,15 @@ func main() {
 		DebugMode:     flags.Debug,
 		Version:       AppVersion,
 		NoTui:         flags.NoTui,
+		Concurrency:   flags.Concurrency,
+		Subdomain:     flags.Subdomain,
+		CustomDomain:  flags.CustomDomain,
+		DomainKey:     loadDomainKey(flags.CustomDomain),
+		Token:         loadToken(),
+		BasicAuth:     flags.BasicAuth,
+		BearerToken:   flags.BearerToken,
+		AllowCIDRs:    flags.AllowCIDRs,
+		DenyCIDRs:     flags.DenyCIDRs,
 	})
 
 	wg.Add(1)
@@ -88,7 +353,14 @@ func main() {
 		}
 	}()
 
-	<-bc.Ready
+	select {
+	case <-bc.Ready:
+	case <-ctx.Done():
+		// Ctrl+C while still connecting to the bore server
+		stop()
+		shutdown(bc, nil, traffik, flags.SaveTraffic)
+		return
+	}
 
 	portCh := make(chan int, 1)
 
@@ -104,7 +376,7 @@ func main() {
 			defer wg.Done()
 
 			err := ws.StartServer()
-			if err != nil {
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
 				fmt.Println("Failed to start bore web client")
 				panic(err)
 			}
@@ -112,11 +384,21 @@ func main() {
 	}
 
 	if !flags.NoTui {
-		p := tea.NewProgram(tui.NewModel(traffik, bc.AppURL, portCh), tea.WithAltScreen())
+		p := tea.NewProgram(tui.NewModel(traffik, bc.AppURL(), bc.URLChanged, portCh), tea.WithAltScreen())
+		go func() {
+			<-ctx.Done()
+			p.Quit()
+		}()
+
 		if _, err := p.Run(); err != nil {
 			fmt.Printf("failed to run TUI: %v", err)
 			os.Exit(1)
 		}
+	} else {
+		<-ctx.Done()
 	}
 
+	stop()
+	shutdown(bc, &ws, traffik, flags.SaveTraffic)
+
 }
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"bore/internal/traffik"
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// trackRequest returns the context a request is handled with, which is
+// cancelled when the server sends a Cancel for it. done must be called once
+// the request is finished.
+func (bc *BoreClient) trackRequest(requestId string) (context.Context, func()) {
+	ctx := context.WithValue(context.Background(), traffik.RequestIDKey, requestId)
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	bc.cancelsMutex.Lock()
+	bc.cancels[requestId] = cancel
+	bc.cancelsMutex.Unlock()
+
+	return ctx, func() {
+		bc.cancelsMutex.Lock()
+		delete(bc.cancels, requestId)
+		bc.cancelsMutex.Unlock()
+
+		cancel(context.Canceled)
+	}
+}
+
+func (bc *BoreClient) cancelRequest(requestId string, reason string) {
+	bc.cancelsMutex.Lock()
+	cancel, ok := bc.cancels[requestId]
+	bc.cancelsMutex.Unlock()
+
+	if ok {
+		cancel(errors.New(reason))
+	}
+
+	bc.streams.CloseStream(requestId)
+}
+
+func (bc *BoreClient) requestCancelled(ctx context.Context, requestId string) {
+	reason := context.Cause(ctx).Error()
+
+	bc.logger.Info("request cancelled by bore server", zap.String("reqId", requestId), zap.String("reason", reason))
+	bc.Traffik.LogCancelled(requestId, reason)
+}
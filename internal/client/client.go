@@ -3,13 +3,23 @@ package client
 import (
 	borepb "bore/borepb"
 	"bore/internal/logger"
+	"bore/internal/mux"
+	"bore/internal/protocol"
 	"bore/internal/traffik"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
@@ -20,6 +30,15 @@ import (
 var BoreServerHost string
 var WSScheme string
 
+var errServerShutdown = errors.New("bore server is shutting down")
+var errClientClosing = errors.New("bore client is shutting down")
+
+const (
+	minReconnectDelay = 500 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+	bodyChunkSize     = 32 * 1024
+)
+
 type BoreClientConfig struct {
 	UpstreamURL   string
 	Traffik       *traffik.Logger
@@ -27,6 +46,15 @@ type BoreClientConfig struct {
 	DebugMode     bool
 	Version       string
 	NoTui         bool
+	Concurrency   int
+	Subdomain     string
+	CustomDomain  string
+	DomainKey     string
+	Token         string
+	BasicAuth     string
+	BearerToken   string
+	AllowCIDRs    []string
+	DenyCIDRs     []string
 }
 
 type BoreClient struct {
@@ -35,131 +63,548 @@ type BoreClient struct {
 	wsMutex       *sync.Mutex
 	debugMode     bool
 	logger        *zap.Logger
+	resumeToken   string
+	readyOnce     sync.Once
+	inFlight      sync.WaitGroup
+	closing       bool
+	closingMutex  sync.Mutex
+	connectCtx    context.Context
+	cancelConnect context.CancelFunc
 	Traffik       *traffik.Logger
-	AppId         string
-	AppURL        string
+	appId         string
+	appURL        string
+	appMutex      sync.Mutex
 	UpstreamURL   string
 	Ready         chan struct{}
+	URLChanged    chan string
 	allowExternal bool
+	workers       chan struct{}
+	tcpAddr       string
+	streams       *mux.Session
+	version       string
+	requestsTotal atomic.Int64
+	statsInterval atomic.Int64
+	cancels       map[string]context.CancelCauseFunc
+	cancelsMutex  sync.Mutex
+	subdomain     string
+	customDomain  string
+	domainKey     string
+	token         string
+	basicAuth     string
+	bearerToken   string
+	allowCIDRs    []string
+	denyCIDRs     []string
+}
+
+func (bc *BoreClient) AppId() string {
+	bc.appMutex.Lock()
+	defer bc.appMutex.Unlock()
+
+	return bc.appId
+}
+
+func (bc *BoreClient) AppURL() string {
+	bc.appMutex.Lock()
+	defer bc.appMutex.Unlock()
+
+	return bc.appURL
+}
+
+// setApp records the app the server assigned, and tells whoever shows the
+// URL about it when it changed on a reconnect.
+func (bc *BoreClient) setApp(appId string, appURL string) (previousAppId string) {
+	bc.appMutex.Lock()
+	previousAppId = bc.appId
+	previousAppURL := bc.appURL
+	bc.appId = appId
+	bc.appURL = appURL
+	bc.appMutex.Unlock()
+
+	if previousAppURL != "" && previousAppURL != appURL {
+		// only the latest URL matters, drop one that wasn't picked up yet
+		select {
+		case <-bc.URLChanged:
+		default:
+		}
+		bc.URLChanged <- appURL
+	}
+
+	return previousAppId
+}
+
+// appLogger tags logs with the current app ID.
+func (bc *BoreClient) appLogger() *zap.Logger {
+	return bc.logger.With(zap.String("appId", bc.AppId()))
+}
+
+// guessAppURL builds the app's URL from the server's host, for older
+// servers that don't send it in the welcome.
+func (bc *BoreClient) guessAppURL(welcome *borepb.Welcome) string {
+	if bc.tcpAddr != "" {
+		host, _, err := net.SplitHostPort(BoreServerHost)
+		if err != nil {
+			host = BoreServerHost
+		}
+		if parts := strings.Split(host, "."); len(parts) > 2 {
+			host = strings.Join(parts[1:], ".")
+		}
+
+		return fmt.Sprintf("tcp://%s", net.JoinHostPort(host, strconv.Itoa(int(welcome.TcpPort))))
+	}
+
+	if welcome.CustomDomain != "" {
+		return fmt.Sprintf("https://%s", welcome.CustomDomain)
+	}
+
+	domain := BoreServerHost
+	if parts := strings.Split(BoreServerHost, "."); len(parts) > 2 {
+		domain = strings.Join(parts[1:], ".")
+	}
+
+	return fmt.Sprintf("https://%s.%s", welcome.AppId, domain)
 }
 
 func (bc *BoreClient) NewWSConnection() error {
+	// shutting down mustn't wait for a slow upgrade or handshake, so the
+	// connection is closed if the client stops before they are done
+	stopConnecting := func() bool { return false }
 	var dialer = websocket.Dialer{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
+		NetDialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			netConn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			stopConnecting = context.AfterFunc(bc.connectCtx, func() {
+				netConn.Close()
+			})
+			return netConn, nil
+		},
 	}
 
 	wsConnStr := fmt.Sprintf("%s://%s/ws", WSScheme, BoreServerHost)
 	bc.logger.Debug("attempting websocket connection", zap.String("url", wsConnStr))
-	conn, res, err := dialer.Dial(wsConnStr, nil)
+	header := http.Header{}
+	header.Set(protocol.Header, strconv.FormatUint(uint64(protocol.Version), 10))
+	conn, res, err := dialer.DialContext(bc.connectCtx, wsConnStr, header)
 
 	if err != nil {
 		bc.logger.Error("failed to establish websocket connection", zap.Error(err), zap.String("url", wsConnStr))
+
+		if errors.Is(err, websocket.ErrBadHandshake) && res != nil {
+			defer res.Body.Close()
+			message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+			// reconnecting won't make the server understand this client
+			if res.StatusCode == http.StatusUpgradeRequired {
+				return &HandshakeError{Message: strings.TrimSpace(string(message))}
+			}
+			if len(message) > 0 {
+				return errors.New(strings.TrimSpace(string(message)))
+			}
+		}
+
 		return err
 	}
 
 	conn.SetPingHandler(func(appData string) error {
 		bc.logger.Debug("received ping from server, sending pong", zap.String("appData", appData))
-		return conn.WriteMessage(websocket.PongMessage, []byte(appData))
+		return conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(5*time.Second))
 	})
 
 	conn.SetCloseHandler(func(code int, text string) error {
 		bc.logger.Warn("websocket connection closed by server", zap.Int("code", code), zap.String("text", text))
-		return conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""))
+		return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(5*time.Second))
 	})
 
-	var domain string = BoreServerHost
+	welcome, err := bc.handshake(conn)
+	stopConnecting()
+	if bc.isClosing() {
+		conn.Close()
+		return errClientClosing
+	}
+	if err != nil {
+		bc.logger.Error("bore server handshake failed", zap.Error(err))
+		conn.Close()
+		return err
+	}
 
-	appId := res.Header.Get("X-Bore-App-ID")
-	parts := strings.Split(BoreServerHost, ".")
+	appId := welcome.AppId
+	connLogger := bc.logger.With(zap.String("appId", appId))
 
-	if len(parts) > 2 {
-		domain = strings.Join(parts[1:], ".")
+	appURL := welcome.PublicUrl
+	if appURL == "" {
+		appURL = bc.guessAppURL(welcome)
+		connLogger.Warn("bore server did not send the public url, it may be wrong", zap.String("appURL", appURL))
 	}
 
+	bc.wsMutex.Lock()
 	bc.wsConn = conn
-	bc.AppId = appId
-	bc.AppURL = fmt.Sprintf("https://%s.%s", appId, domain)
-	bc.Ready <- struct{}{}
-	close(bc.Ready)
+	bc.wsMutex.Unlock()
 
-	bc.logger = bc.logger.With(zap.String("appId", appId))
-	bc.logger.Info("websocket connection established", zap.String("appURL", bc.AppURL))
+	previousAppId := bc.setApp(appId, appURL)
+	if previousAppId != "" && previousAppId != appId {
+		connLogger.Warn("could not resume previous app, bore URL has changed", zap.String("previousAppId", previousAppId), zap.String("appURL", appURL))
+	}
+	bc.resumeToken = welcome.ResumeToken
+
+	bc.readyOnce.Do(func() {
+		bc.Ready <- struct{}{}
+		close(bc.Ready)
+	})
+
+	connLogger.Info("websocket connection established", zap.String("appURL", appURL), zap.Uint32("protocolVersion", welcome.ProtocolVersion), zap.String("serverVersion", welcome.ServerVersion))
 
 	return nil
 }
 
+// reconnect retries until the connection is back, unless the server refuses
+// the client outright.
+func (bc *BoreClient) reconnect() error {
+	for attempt := 0; ; attempt++ {
+		if bc.isClosing() {
+			return errClientClosing
+		}
+
+		delay := reconnectDelay(attempt)
+		bc.logger.Info("reconnecting to bore server", zap.Int("attempt", attempt+1), zap.Duration("delay", delay))
+		select {
+		case <-time.After(delay):
+		case <-bc.connectCtx.Done():
+			return errClientClosing
+		}
+
+		err := bc.NewWSConnection()
+		if err == nil {
+			return nil
+		}
+
+		var handshakeErr *HandshakeError
+		if errors.As(err, &handshakeErr) {
+			return err
+		}
+	}
+}
+
+func reconnectDelay(attempt int) time.Duration {
+	delay := maxReconnectDelay
+	if attempt < 16 {
+		delay = min(minReconnectDelay<<attempt, maxReconnectDelay)
+	}
+
+	return delay/2 + rand.N(delay/2)
+}
+
 func (bc *BoreClient) HandleWSMessages() error {
-	defer bc.resty.Close()
+	defer bc.streams.Reset(mux.ErrSessionReset)
 
-	bc.logger.Info("starting to handle websocket messages")
+	logger := bc.appLogger()
+	logger.Info("starting to handle websocket messages", zap.Int("concurrency", cap(bc.workers)))
 	for {
 		_, message, err := bc.wsConn.ReadMessage()
+		if err != nil && bc.isClosing() {
+			return err
+		}
 		if err != nil {
-			bc.logger.Error("error reading websocket message", zap.Error(err))
+			logger.Error("error reading websocket message", zap.Error(err))
 			return err
 		}
 
-		var request borepb.Request
+		envelope := &borepb.Envelope{}
 
-		err = proto.Unmarshal(message, &request)
+		err = proto.Unmarshal(message, envelope)
 		if err != nil {
-			bc.logger.Error("failed to unmarshal protobuf message", zap.Error(err))
+			logger.Error("failed to unmarshal protobuf message", zap.Error(err))
 			return err
 		}
 
-		bc.logger.Debug("received request", zap.String("reqId", request.Id), zap.String("method", request.Method), zap.String("path", request.Path))
+		switch message := envelope.Message.(type) {
+		case *borepb.Envelope_Request:
+			bc.dispatchRequest(message.Request)
 
-		cookies, _ := http.ParseCookie(request.Cookies)
+		case *borepb.Envelope_Frame:
+			bc.streams.Dispatch(mux.FrameFromProto(message.Frame))
 
-		ctx := context.WithValue(context.TODO(), traffik.RequestIDKey, request.Id)
+		case *borepb.Envelope_Cancel:
+			bc.cancelRequest(message.Cancel.Id, message.Cancel.Reason)
 
-		req := bc.resty.
-			NewRequest().
-			SetContext(ctx).
-			SetMethod(request.Method).
-			SetURL(request.Path).
-			SetBody(request.Body).
-			SetCookies(cookies).
-			SetHeaders(request.Headers)
+		case *borepb.Envelope_ConfigUpdate:
+			bc.applyConfigUpdate(message.ConfigUpdate)
 
-		bc.Traffik.LogRequest(req)
+		case *borepb.Envelope_Blocked:
+			logger.Warn("bore server blocked a visitor", zap.String("reqId", message.Blocked.Id), zap.String("remoteAddr", message.Blocked.RemoteAddr), zap.String("reason", message.Blocked.Reason))
+			bc.Traffik.LogBlocked(message.Blocked)
 
-		res, err := req.Send()
-		if err != nil {
-			bc.logger.Error("failed to send request", zap.String("reqId", request.Id), zap.Error(err))
-			return err
+		case *borepb.Envelope_Error:
+			logger.Warn("bore server reported an error", zap.String("reqId", message.Error.Id), zap.String("error", message.Error.Message))
+
+		case *borepb.Envelope_Shutdown:
+			logger.Info("bore server is shutting down", zap.String("reason", message.Shutdown.Reason))
+			return errServerShutdown
+
+		default:
+			messageType := fmt.Sprintf("%T", envelope.Message)
+			logger.Warn("dropping unexpected message from bore server", zap.String("type", messageType))
+
+			// the server only logs errors, so this can't bounce back and forth
+			err := bc.send(&borepb.Envelope{
+				Message: &borepb.Envelope_Error{Error: &borepb.Error{
+					Message: fmt.Sprintf("bore client can't handle message of type %s", messageType),
+				}},
+			})
+			if err != nil {
+				logger.Debug("failed to report unexpected message to bore server", zap.Error(err))
+			}
 		}
+	}
+}
+
+func (bc *BoreClient) dispatchRequest(request *borepb.Request) {
+	bc.logger.Debug("received request", zap.String("reqId", request.Id), zap.String("method", request.Method), zap.String("path", request.Path))
 
-		bc.logger.Debug("response received", zap.String("reqId", request.Id), zap.Int("statusCode", res.StatusCode()))
-		bc.Traffik.LogResponse(res)
+	stream := bc.streams.Open(request.Id)
+	bc.requestsTotal.Add(1)
 
-		response := borepb.Response{
-			Id:         request.Id,
-			StatusCode: int32(res.StatusCode()),
-			Body:       res.Bytes(),
-			Timestamp:  res.ReceivedAt().UnixMilli(),
-			Headers:    make(map[string]string),
+	// websockets and tcp connections are long lived, so they don't hold
+	// on to a worker, and aren't waited for on shutdown. Cancelling them
+	// closes their stream.
+	if request.Upgrade {
+		go bc.handleWebSocket(request, stream)
+		return
+	}
+
+	if bc.tcpAddr != "" {
+		go bc.handleTCPConn(request, stream)
+		return
+	}
+
+	if !bc.acquireInFlight() {
+		bc.writeResponse(&borepb.Response{Id: request.Id, Error: protocol.ErrorShuttingDown})
+		stream.Close()
+		return
+	}
+
+	ctx, done := bc.trackRequest(request.Id)
+
+	go func() {
+		defer func() {
+			done()
+			bc.inFlight.Done()
+		}()
+
+		select {
+		case bc.workers <- struct{}{}:
+		case <-ctx.Done():
+			stream.Close()
+			bc.logger.Debug("request cancelled before a worker was free", zap.String("reqId", request.Id))
+			return
 		}
+		defer func() {
+			<-bc.workers
+		}()
 
-		for headerName, headerValues := range res.Header() {
-			response.Headers[headerName] = strings.Join(headerValues, ",")
+		err := bc.handleRequest(ctx, request, stream)
+		if err != nil {
+			bc.logger.Error("failed to handle request", zap.String("reqId", request.Id), zap.Error(err))
+		}
+	}()
+}
+
+func (bc *BoreClient) handleRequest(ctx context.Context, request *borepb.Request, stream *mux.Stream) error {
+	defer stream.Close()
+
+	cookies, _ := http.ParseCookie(request.Cookies)
+
+	req := bc.resty.
+		NewRequest().
+		SetContext(ctx).
+		SetMethod(request.Method).
+		SetURL(request.Path).
+		SetBody(request.Body).
+		SetCookies(cookies).
+		SetHeaders(request.Headers).
+		SetDoNotParseResponse(true)
+
+	// net/http closes request bodies once they are sent, which would release
+	// the stream before the response is streamed back on it
+	if request.Frame == borepb.FrameType_FRAME_START {
+		req.SetBody(io.NopCloser(stream))
+	}
+
+	bc.Traffik.LogRequest(req)
+
+	res, err := req.Send()
+	if err != nil && ctx.Err() != nil {
+		bc.requestCancelled(ctx, request.Id)
+		return nil
+	}
+
+	if err != nil {
+		bc.logger.Error("failed to send request", zap.String("reqId", request.Id), zap.Error(err))
+		return bc.writeUpstreamError(req, request.Id, err)
+	}
+
+	defer res.Body.Close()
+
+	bc.logger.Debug("response received", zap.String("reqId", request.Id), zap.Int("statusCode", res.StatusCode()))
+	bc.Traffik.LogResponse(res)
+
+	response := &borepb.Response{
+		Id:         request.Id,
+		StatusCode: int32(res.StatusCode()),
+		Timestamp:  res.ReceivedAt().UnixMilli(),
+		Headers:    make(map[string]string),
+	}
+
+	for headerName, headerValues := range res.Header() {
+		response.Headers[headerName] = strings.Join(headerValues, ",")
+	}
+
+	contentLength := res.RawResponse.ContentLength
+	if contentLength >= 0 && contentLength <= bodyChunkSize {
+		response.Body, err = io.ReadAll(res.Body)
+		if err != nil && ctx.Err() != nil {
+			bc.requestCancelled(ctx, request.Id)
+			return nil
 		}
 
-		resBytes, err := proto.Marshal(&response)
 		if err != nil {
-			bc.logger.Error("failed to marshal response", zap.String("reqId", request.Id), zap.Error(err))
+			bc.logger.Error("failed to read response body", zap.String("reqId", request.Id), zap.Error(err))
+			return bc.writeUpstreamError(req, request.Id, err)
+		}
+
+		return bc.writeResponse(response)
+	}
+
+	response.Frame = borepb.FrameType_FRAME_START
+
+	err = bc.writeResponse(response)
+	if err != nil {
+		return err
+	}
+
+	err = bc.streamResponseBody(ctx, stream, res.Body)
+	if err != nil && ctx.Err() != nil {
+		bc.requestCancelled(ctx, request.Id)
+		return nil
+	}
+
+	return err
+}
+
+func (bc *BoreClient) streamResponseBody(ctx context.Context, stream *mux.Stream, body io.Reader) error {
+	buf := make([]byte, bodyChunkSize)
+
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			_, writeErr := stream.Write(buf[:n])
+			if writeErr != nil {
+				return writeErr
+			}
+		}
+
+		if err == io.EOF {
+			return stream.CloseWrite()
+		}
+
+		if err != nil && ctx.Err() != nil {
 			return err
 		}
 
-		bc.wsMutex.Lock()
-		err = bc.wsConn.WriteMessage(websocket.BinaryMessage, resBytes)
-		bc.wsMutex.Unlock()
 		if err != nil {
-			bc.logger.Error("failed to write response to websocket", zap.String("reqId", request.Id), zap.Error(err))
-			return err
+			bc.logger.Error("failed to read response body", zap.String("reqId", stream.ID()), zap.Error(err))
+
+			writeErr := stream.CloseWithError(bc.describeUpstreamError(err))
+
+			return errors.Join(err, writeErr)
 		}
-		bc.logger.Debug("response sent", zap.String("reqId", request.Id))
+	}
+}
+
+func (bc *BoreClient) writeUpstreamError(req *resty.Request, requestId string, err error) error {
+	message := bc.describeUpstreamError(err)
+	bc.Traffik.LogError(req, message)
+
+	return bc.writeResponse(&borepb.Response{
+		Id:         requestId,
+		StatusCode: http.StatusBadGateway,
+		Timestamp:  time.Now().UnixMilli(),
+		Error:      message,
+	})
+}
+
+// Streamed request bodies are plain readers, so net/http can't tell their
+// length and falls back to chunked encoding unless we carry it over.
+func preserveContentLength(_ *resty.Client, req *resty.Request) error {
+	raw := req.RawRequest
+	if raw == nil || raw.Body == nil || raw.ContentLength != 0 {
+		return nil
+	}
+
+	contentLength, err := strconv.ParseInt(req.Header.Get("Content-Length"), 10, 64)
+	if err == nil && contentLength > 0 {
+		raw.ContentLength = contentLength
+	}
+
+	return nil
+}
+
+func (bc *BoreClient) sendFrame(frame mux.Frame) error {
+	err := bc.send(&borepb.Envelope{
+		Message: &borepb.Envelope_Frame{Frame: frame.Proto()},
+	})
+	if err != nil {
+		bc.logger.Error("failed to write frame to websocket", zap.String("reqId", frame.StreamID), zap.Error(err))
+	}
+
+	return err
+}
+
+func (bc *BoreClient) writeResponse(response *borepb.Response) error {
+	err := bc.send(&borepb.Envelope{
+		Message: &borepb.Envelope_Response{Response: response},
+	})
+	if err != nil {
+		bc.logger.Error("failed to write response to websocket", zap.String("reqId", response.Id), zap.Error(err))
+		return err
+	}
+	bc.logger.Debug("response sent", zap.String("reqId", response.Id))
+
+	return nil
+}
+
+func (bc *BoreClient) send(envelope *borepb.Envelope) error {
+	message, err := proto.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	bc.wsMutex.Lock()
+	defer bc.wsMutex.Unlock()
+
+	return bc.wsConn.WriteMessage(websocket.BinaryMessage, message)
+}
+
+func (bc *BoreClient) describeUpstreamError(err error) string {
+	upstream := bc.UpstreamURL
+	if parsed, parseErr := url.Parse(bc.UpstreamURL); parseErr == nil && parsed.Host != "" {
+		upstream = parsed.Host
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Sprintf("upstream at %s refused connection", upstream)
+	case errors.As(err, &dnsErr):
+		return fmt.Sprintf("upstream host %s could not be resolved", upstream)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Sprintf("upstream at %s timed out", upstream)
+	default:
+		return fmt.Sprintf("upstream at %s could not be reached (%v)", upstream, err)
 	}
 }
 
@@ -180,21 +625,49 @@ func (bc *BoreClient) RegisterApp() error {
 	}
 
 	err = bc.NewWSConnection()
+	if bc.isClosing() {
+		bc.logger.Info("bore client was stopped while connecting")
+		return nil
+	}
 	if err != nil {
 		bc.logger.Error("failed to establish websocket connection during registration", zap.Error(err))
 		return err
 	}
 
-	err = bc.HandleWSMessages()
-	if err != nil {
-		bc.logger.Error("error handling websocket messages", zap.Error(err))
-	}
+	statsCtx, stopStats := context.WithCancel(bc.connectCtx)
+	defer stopStats()
+	go bc.reportStats(statsCtx)
 
-	return err
+	for {
+		err = bc.HandleWSMessages()
+		if bc.isClosing() {
+			bc.logger.Info("closed websocket connection to bore server")
+			return nil
+		}
+
+		bc.logger.Warn("lost websocket connection to bore server", zap.Error(err))
+		bc.wsConn.Close()
+
+		// the server closes with a policy violation when an operator kicks the app
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) && closeErr.Code == websocket.ClosePolicyViolation {
+			return fmt.Errorf("bore server closed the tunnel: %s", closeErr.Text)
+		}
+
+		err = bc.reconnect()
+		if err == errClientClosing {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
 }
 
 func NewBoreClient(boreClientCfg *BoreClientConfig) *BoreClient {
-	resty := resty.New().SetBaseURL(boreClientCfg.UpstreamURL)
+	resty := resty.New().
+		SetBaseURL(boreClientCfg.UpstreamURL).
+		SetRequestMiddlewares(resty.PrepareRequestMiddleware, preserveContentLength)
 	logFilePath := "./logs/bore-client.log"
 
 	cfg := logger.
@@ -211,9 +684,19 @@ func NewBoreClient(boreClientCfg *BoreClientConfig) *BoreClient {
 		panic(err)
 	}
 
-	logger.Info("bore client initialized", zap.String("upstreamURL", boreClientCfg.UpstreamURL), zap.Bool("debugMode", boreClientCfg.DebugMode), zap.Bool("allowExternal", boreClientCfg.AllowExternal))
+	concurrency := boreClientCfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	logger.Info("bore client initialized", zap.String("upstreamURL", boreClientCfg.UpstreamURL), zap.Bool("debugMode", boreClientCfg.DebugMode), zap.Bool("allowExternal", boreClientCfg.AllowExternal), zap.Int("concurrency", concurrency))
 
-	return &BoreClient{
+	var tcpAddr string
+	if upstream, err := url.Parse(boreClientCfg.UpstreamURL); err == nil && upstream.Scheme == "tcp" {
+		tcpAddr = upstream.Host
+	}
+
+	bc := &BoreClient{
 		resty:         resty,
 		UpstreamURL:   boreClientCfg.UpstreamURL,
 		debugMode:     boreClientCfg.DebugMode,
@@ -221,6 +704,24 @@ func NewBoreClient(boreClientCfg *BoreClientConfig) *BoreClient {
 		Traffik:       boreClientCfg.Traffik,
 		wsMutex:       &sync.Mutex{},
 		Ready:         make(chan struct{}),
+		URLChanged:    make(chan string, 1),
 		allowExternal: boreClientCfg.AllowExternal,
+		workers:       make(chan struct{}, concurrency),
+		tcpAddr:       tcpAddr,
+		version:       boreClientCfg.Version,
+		cancels:       make(map[string]context.CancelCauseFunc),
+		subdomain:     strings.ToLower(boreClientCfg.Subdomain),
+		customDomain:  strings.ToLower(boreClientCfg.CustomDomain),
+		domainKey:     boreClientCfg.DomainKey,
+		token:         boreClientCfg.Token,
+		basicAuth:     boreClientCfg.BasicAuth,
+		bearerToken:   boreClientCfg.BearerToken,
+		allowCIDRs:    boreClientCfg.AllowCIDRs,
+		denyCIDRs:     boreClientCfg.DenyCIDRs,
 	}
+	bc.connectCtx, bc.cancelConnect = context.WithCancel(context.Background())
+	bc.streams = mux.NewSession(bc.sendFrame)
+	bc.statsInterval.Store(int64(defaultStatsInterval))
+
+	return bc
 }
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenPath is where `bore login` stores the API token, e.g.
+// ~/.config/bore/token on Linux.
+func TokenPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "bore", "token"), nil
+}
+
+// SaveToken stores token so later runs present it to the bore server.
+func SaveToken(token string) (string, error) {
+	path, err := TokenPath()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.WriteFile(path, []byte(token+"\n"), 0600)
+}
+
+// DomainKeyPath is where the key that custom domain TXT records are derived
+// from is kept, e.g. ~/.config/bore/domain-key on Linux.
+func DomainKeyPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "bore", "domain-key"), nil
+}
+
+// LoadDomainKey returns the stored domain key, creating one on first use.
+// Only its holder can claim the domains whose TXT records match it.
+func LoadDomainKey() (string, error) {
+	path, err := DomainKeyPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(secret)
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return "", err
+	}
+
+	return key, os.WriteFile(path, []byte(key+"\n"), 0600)
+}
+
+// LoadToken returns the stored token, or "" if `bore login` was never run.
+func LoadToken() (string, error) {
+	path, err := TokenPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	borepb "bore/borepb"
+	"bore/internal/protocol"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// HandshakeError means the bore server refused the client, e.g. because one
+// of them is out of date. Reconnecting won't help.
+type HandshakeError struct {
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return e.Message
+}
+
+// handshake sends the client's hello and waits for the server's welcome.
+func (bc *BoreClient) handshake(conn *websocket.Conn) (*borepb.Welcome, error) {
+	features := []string{protocol.FeatureWebSocket, protocol.FeatureResume}
+	if bc.tcpAddr != "" {
+		features = append(features, protocol.FeatureTCP)
+	}
+
+	hello, err := proto.Marshal(&borepb.Hello{
+		ProtocolVersion: protocol.Version,
+		ClientVersion:   bc.version,
+		Features:        features,
+		ResumeToken:     bc.resumeToken,
+		Subdomain:       bc.subdomain,
+		CustomDomain:    bc.customDomain,
+		DomainKey:       bc.domainKey,
+		Token:           bc.token,
+		BasicAuth:       bc.basicAuth,
+		BearerToken:     bc.bearerToken,
+		AllowCidrs:      bc.allowCIDRs,
+		DenyCidrs:       bc.denyCIDRs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.WriteMessage(websocket.BinaryMessage, hello)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(protocol.HandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return nil, fmt.Errorf("no welcome from bore server: %w", err)
+	}
+
+	welcome := &borepb.Welcome{}
+	err = proto.Unmarshal(message, welcome)
+	if err != nil {
+		return nil, err
+	}
+
+	if welcome.Error != "" {
+		return nil, &HandshakeError{Message: welcome.Error}
+	}
+
+	_, err = protocol.Negotiate(welcome.ProtocolVersion)
+	if err != nil {
+		return nil, &HandshakeError{Message: fmt.Sprintf("The bore server is out of date: %s.", err)}
+	}
+
+	return welcome, nil
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	borepb "bore/borepb"
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// acquireInFlight counts a request that has to finish before the client
+// shuts down. It fails once Shutdown has started.
+func (bc *BoreClient) acquireInFlight() bool {
+	bc.closingMutex.Lock()
+	defer bc.closingMutex.Unlock()
+
+	if bc.closing {
+		return false
+	}
+
+	bc.inFlight.Add(1)
+	return true
+}
+
+func (bc *BoreClient) isClosing() bool {
+	bc.closingMutex.Lock()
+	defer bc.closingMutex.Unlock()
+
+	return bc.closing
+}
+
+// Shutdown turns away new requests, waits for in-flight ones until ctx is
+// done, and then closes the tunnel so the server can release it right away
+// instead of holding it for a reconnect.
+func (bc *BoreClient) Shutdown(ctx context.Context) error {
+	bc.closingMutex.Lock()
+	bc.closing = true
+	bc.closingMutex.Unlock()
+	bc.cancelConnect()
+
+	defer bc.logger.Sync()
+
+	logger := bc.appLogger()
+
+	drained := make(chan struct{})
+	go func() {
+		bc.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		logger.Info("finished in-flight requests")
+	case <-ctx.Done():
+		logger.Warn("gave up waiting for in-flight requests", zap.Error(ctx.Err()))
+	}
+
+	bc.wsMutex.Lock()
+	conn := bc.wsConn
+	bc.wsMutex.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	err := bc.send(&borepb.Envelope{
+		Message: &borepb.Envelope_Shutdown{Shutdown: &borepb.Shutdown{Reason: "bore client is shutting down"}},
+	})
+	if err != nil {
+		logger.Debug("failed to send shutdown to bore server", zap.Error(err))
+	}
+
+	err = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	if err != nil {
+		logger.Debug("failed to send close frame to bore server", zap.Error(err))
+	}
+
+	return conn.Close()
+}
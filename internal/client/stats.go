@@ -0,0 +1,52 @@
+package client
+
+import (
+	borepb "bore/borepb"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultStatsInterval = 30 * time.Second
+
+func (bc *BoreClient) applyConfigUpdate(update *borepb.ConfigUpdate) {
+	if update.StatsIntervalMs > 0 {
+		bc.statsInterval.Store(update.StatsIntervalMs * int64(time.Millisecond))
+	}
+
+	bc.logger.Debug("applied config update from bore server", zap.Int64("statsIntervalMs", update.StatsIntervalMs))
+}
+
+// reportStats periodically tells the server how busy the tunnel is, until
+// ctx is done. Stats sent while disconnected are simply lost.
+func (bc *BoreClient) reportStats(ctx context.Context) {
+	interval := time.Duration(bc.statsInterval.Load())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// the server may have changed the interval since the last tick
+		if current := time.Duration(bc.statsInterval.Load()); current != interval {
+			interval = current
+			ticker.Reset(interval)
+		}
+
+		err := bc.send(&borepb.Envelope{
+			Message: &borepb.Envelope_Stats{Stats: &borepb.Stats{
+				OpenStreams:   int64(bc.streams.Len()),
+				RequestsTotal: bc.requestsTotal.Load(),
+				Timestamp:     time.Now().UnixMilli(),
+			}},
+		})
+		if err != nil {
+			bc.logger.Debug("failed to send stats to bore server", zap.Error(err))
+		}
+	}
+}
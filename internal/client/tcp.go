@@ -0,0 +1,46 @@
+package client
+
+import (
+	borepb "bore/borepb"
+	"bore/internal/mux"
+	"io"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// handleTCPConn dials the local service for a connection accepted on the
+// server's public port and relays raw bytes both ways until either side closes.
+func (bc *BoreClient) handleTCPConn(request *borepb.Request, stream *mux.Stream) {
+	defer stream.Close()
+
+	connLogger := bc.appLogger().With(zap.String("reqId", request.Id), zap.String("clientIP", request.Headers["X-Forwarded-For"]))
+	connLogger.Debug("new tcp connection", zap.String("upstream", bc.tcpAddr))
+
+	conn, err := net.DialTimeout("tcp", bc.tcpAddr, 10*time.Second)
+	if err != nil {
+		connLogger.Error("failed to dial upstream", zap.Error(err))
+		stream.CloseWithError(bc.describeUpstreamError(err))
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		_, err := io.Copy(conn, stream)
+		if err == nil {
+			// the visitor is done sending, but may still be reading
+			if tcpConn, ok := conn.(*net.TCPConn); ok {
+				tcpConn.CloseWrite()
+				return
+			}
+		}
+		conn.Close()
+	}()
+
+	_, err = io.Copy(stream, conn)
+	if err != nil {
+		connLogger.Debug("tcp connection closed", zap.Error(err))
+	}
+	stream.CloseWrite()
+}
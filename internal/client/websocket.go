@@ -0,0 +1,190 @@
+package client
+
+import (
+	borepb "bore/borepb"
+	"bore/internal/mux"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// handshake headers gorilla sets itself and refuses to have passed in
+var websocketHandshakeHeaders = []string{
+	"Upgrade",
+	"Connection",
+	"Sec-Websocket-Key",
+	"Sec-Websocket-Version",
+	"Sec-Websocket-Extensions",
+	"Sec-Websocket-Accept",
+}
+
+// handleWebSocket dials the upstream for an upgrade request and relays
+// messages between it and the bore server until either side closes.
+func (bc *BoreClient) handleWebSocket(request *borepb.Request, stream *mux.Stream) {
+	defer stream.Close()
+
+	reqLogger := bc.appLogger().With(zap.String("reqId", request.Id))
+	requestedAt := time.Now()
+
+	upstreamURL, err := bc.websocketURL(request.Path)
+	if err != nil {
+		reqLogger.Error("failed to build upstream websocket url", zap.Error(err))
+		bc.writeWebSocketError(request, requestedAt, err)
+		return
+	}
+
+	header := http.Header{}
+	for headerName, headerValue := range request.Headers {
+		header.Set(headerName, headerValue)
+	}
+	for _, headerName := range websocketHandshakeHeaders {
+		header.Del(headerName)
+	}
+	if request.Cookies != "" {
+		header.Set("Cookie", request.Cookies)
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+	}
+
+	upstreamConn, res, err := dialer.Dial(upstreamURL, header)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && res != nil {
+			reqLogger.Info("upstream rejected websocket handshake", zap.Int("statusCode", res.StatusCode))
+			bc.writeHandshakeRejection(request, requestedAt, res)
+			return
+		}
+
+		reqLogger.Error("failed to dial upstream websocket", zap.Error(err))
+		bc.writeWebSocketError(request, requestedAt, err)
+		return
+	}
+	defer upstreamConn.Close()
+
+	for _, headerName := range websocketHandshakeHeaders {
+		res.Header.Del(headerName)
+	}
+
+	response := &borepb.Response{
+		Id:         request.Id,
+		StatusCode: http.StatusSwitchingProtocols,
+		Timestamp:  time.Now().UnixMilli(),
+		Headers:    flattenHeader(res.Header),
+		Frame:      borepb.FrameType_FRAME_START,
+	}
+
+	bc.Traffik.LogWebSocket(request.Id, request, response, requestedAt)
+
+	err = bc.writeResponse(response)
+	if err != nil {
+		return
+	}
+
+	reqLogger.Debug("websocket passthrough established", zap.String("url", upstreamURL))
+
+	go func() {
+		for {
+			messageType, data, err := stream.ReadMessage()
+			if err != nil {
+				// END from the server means the visitor went away
+				upstreamConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(5*time.Second))
+				upstreamConn.Close()
+				return
+			}
+
+			err = upstreamConn.WriteMessage(int(messageType), data)
+			if err != nil {
+				reqLogger.Debug("failed to write websocket message to upstream", zap.Error(err))
+				upstreamConn.Close()
+				return
+			}
+		}
+	}()
+
+	defer stream.CloseWrite()
+
+	for {
+		messageType, data, err := upstreamConn.ReadMessage()
+		if err != nil {
+			reqLogger.Debug("upstream websocket closed", zap.Error(err))
+			return
+		}
+
+		err = stream.WriteMessage(int32(messageType), data)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (bc *BoreClient) websocketURL(path string) (string, error) {
+	upstream, err := url.Parse(bc.UpstreamURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch upstream.Scheme {
+	case "https":
+		upstream.Scheme = "wss"
+	default:
+		upstream.Scheme = "ws"
+	}
+
+	target, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	upstream.Path = strings.TrimSuffix(upstream.Path, "/") + target.Path
+	upstream.RawQuery = target.RawQuery
+
+	return upstream.String(), nil
+}
+
+// writeHandshakeRejection forwards the upstream's non-101 answer to the
+// visitor as a regular response.
+func (bc *BoreClient) writeHandshakeRejection(request *borepb.Request, requestedAt time.Time, res *http.Response) {
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, bodyChunkSize))
+
+	response := &borepb.Response{
+		Id:         request.Id,
+		StatusCode: int32(res.StatusCode),
+		Timestamp:  time.Now().UnixMilli(),
+		Headers:    flattenHeader(res.Header),
+		Body:       body,
+	}
+
+	bc.Traffik.LogWebSocket(request.Id, request, response, requestedAt)
+	bc.writeResponse(response)
+}
+
+func (bc *BoreClient) writeWebSocketError(request *borepb.Request, requestedAt time.Time, err error) {
+	response := &borepb.Response{
+		Id:         request.Id,
+		StatusCode: http.StatusBadGateway,
+		Timestamp:  time.Now().UnixMilli(),
+		Error:      bc.describeUpstreamError(err),
+	}
+
+	bc.Traffik.LogWebSocket(request.Id, request, response, requestedAt)
+	bc.writeResponse(response)
+}
+
+func flattenHeader(header http.Header) map[string]string {
+	flattened := make(map[string]string)
+	for headerName, headerValues := range header {
+		flattened[headerName] = strings.Join(headerValues, ",")
+	}
+
+	return flattened
+}
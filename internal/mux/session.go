@@ -0,0 +1,174 @@
+// Package mux multiplexes flow-controlled logical streams over the single
+// websocket between a bore client and the bore server.
+//
+// Every request, websocket passthrough and tcp connection is a stream keyed
+// by its request id. A peer may only send as many DATA bytes on a stream as
+// the other side has granted it, starting at DefaultWindow and topped up with
+// WINDOW frames as the reader consumes data. The websocket read loop therefore
+// never blocks on a slow consumer, and one bulk transfer can't starve the
+// other streams sharing the connection.
+package mux
+
+import (
+	borepb "bore/borepb"
+	"errors"
+	"sync"
+)
+
+const (
+	// DefaultWindow is the number of unread bytes a stream will buffer.
+	DefaultWindow = 256 * 1024
+
+	// MaxFrameSize is the largest DATA payload Write puts in a single frame.
+	MaxFrameSize = 32 * 1024
+)
+
+var (
+	ErrStreamClosed = errors.New("stream closed")
+	ErrStreamReset  = errors.New("stream reset by peer")
+	ErrSessionReset = errors.New("session reset")
+
+	// ErrWindowExceeded fails a stream whose peer kept sending after using up
+	// the window it was granted.
+	ErrWindowExceeded = errors.New("stream window exceeded by peer")
+)
+
+// Frame is the in-memory form of a DATA, END, WINDOW or RESET frame. The
+// client and server send it as a borepb.Frame.
+type Frame struct {
+	StreamID    string
+	Type        borepb.FrameType
+	MessageType int32
+	Data        []byte
+	Window      uint32
+	Error       string
+}
+
+// FrameFromProto converts a frame received from the peer.
+func FrameFromProto(frame *borepb.Frame) Frame {
+	return Frame{
+		StreamID:    frame.StreamId,
+		Type:        frame.Type,
+		MessageType: frame.MessageType,
+		Data:        frame.Data,
+		Window:      frame.Window,
+		Error:       frame.Error,
+	}
+}
+
+// Proto converts the frame for sending to the peer.
+func (frame Frame) Proto() *borepb.Frame {
+	return &borepb.Frame{
+		StreamId:    frame.StreamID,
+		Type:        frame.Type,
+		MessageType: frame.MessageType,
+		Data:        frame.Data,
+		Window:      frame.Window,
+		Error:       frame.Error,
+	}
+}
+
+type Session struct {
+	send    func(Frame) error
+	mutex   sync.Mutex
+	streams map[string]*Stream
+}
+
+// NewSession returns a session that writes its frames with send. send must
+// be safe for concurrent use.
+func NewSession(send func(Frame) error) *Session {
+	return &Session{
+		send:    send,
+		streams: make(map[string]*Stream),
+	}
+}
+
+// Open registers a stream for id. Frames for ids without an open stream are
+// dropped.
+func (s *Session) Open(id string) *Stream {
+	stream := newStream(id, s)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if old, ok := s.streams[id]; ok {
+		old.reset(ErrStreamClosed)
+	}
+	s.streams[id] = stream
+
+	return stream
+}
+
+// Dispatch routes a DATA, END, WINDOW or RESET frame to its stream. It never
+// blocks, and reports whether the stream was open. DATA for a stream that
+// isn't open, or beyond the window granted for it, is answered with a RESET,
+// so the peer stops sending.
+func (s *Session) Dispatch(frame Frame) bool {
+	s.mutex.Lock()
+	stream, ok := s.streams[frame.StreamID]
+	s.mutex.Unlock()
+
+	if !ok {
+		if frame.Type == borepb.FrameType_FRAME_DATA {
+			s.send(Frame{StreamID: frame.StreamID, Type: borepb.FrameType_FRAME_RESET})
+		}
+		return false
+	}
+
+	switch frame.Type {
+	case borepb.FrameType_FRAME_DATA:
+		if !stream.deliver(frame) {
+			s.remove(stream)
+			s.send(Frame{StreamID: frame.StreamID, Type: borepb.FrameType_FRAME_RESET})
+		}
+	case borepb.FrameType_FRAME_END:
+		stream.end(frame.Error)
+	case borepb.FrameType_FRAME_WINDOW:
+		stream.grant(int(frame.Window))
+	case borepb.FrameType_FRAME_RESET:
+		stream.reset(ErrStreamReset)
+		s.remove(stream)
+	}
+
+	return true
+}
+
+// Reset fails every open stream with err, for when the websocket carrying
+// them has gone away.
+func (s *Session) Reset(err error) {
+	s.mutex.Lock()
+	streams := s.streams
+	s.streams = make(map[string]*Stream)
+	s.mutex.Unlock()
+
+	for _, stream := range streams {
+		stream.reset(err)
+	}
+}
+
+// CloseStream closes the stream for id, if it is open.
+func (s *Session) CloseStream(id string) {
+	s.mutex.Lock()
+	stream, ok := s.streams[id]
+	s.mutex.Unlock()
+
+	if ok {
+		stream.Close()
+	}
+}
+
+func (s *Session) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.streams)
+}
+
+func (s *Session) remove(stream *Stream) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.streams[stream.id] == stream {
+		delete(s.streams, stream.id)
+	}
+}
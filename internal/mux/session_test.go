@@ -0,0 +1,122 @@
+package mux
+
+import (
+	borepb "bore/borepb"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recorder collects the frames a session sends to its peer.
+type recorder struct {
+	mutex  sync.Mutex
+	frames []Frame
+}
+
+func (r *recorder) send(frame Frame) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.frames = append(r.frames, frame)
+	return nil
+}
+
+func (r *recorder) sent(frameType borepb.FrameType) []Frame {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	frames := []Frame{}
+	for _, frame := range r.frames {
+		if frame.Type == frameType {
+			frames = append(frames, frame)
+		}
+	}
+
+	return frames
+}
+
+func newTestSession() (*Session, *recorder) {
+	r := &recorder{}
+	return NewSession(r.send), r
+}
+
+func dataFrame(id string, n int) Frame {
+	return Frame{StreamID: id, Type: borepb.FrameType_FRAME_DATA, Data: make([]byte, n)}
+}
+
+func TestDispatchDataForUnknownStreamSendsReset(t *testing.T) {
+	session, r := newTestSession()
+
+	if session.Dispatch(dataFrame("missing", 10)) {
+		t.Fatal("Dispatch reported an unknown stream as open")
+	}
+
+	resets := r.sent(borepb.FrameType_FRAME_RESET)
+	if len(resets) != 1 || resets[0].StreamID != "missing" {
+		t.Fatalf("want one RESET for the unknown stream, got %+v", resets)
+	}
+}
+
+func TestDispatchControlFramesForUnknownStreamAreDropped(t *testing.T) {
+	session, r := newTestSession()
+
+	for _, frameType := range []borepb.FrameType{borepb.FrameType_FRAME_END, borepb.FrameType_FRAME_WINDOW, borepb.FrameType_FRAME_RESET} {
+		if session.Dispatch(Frame{StreamID: "missing", Type: frameType, Window: 10}) {
+			t.Fatalf("Dispatch reported an unknown stream as open for %v", frameType)
+		}
+	}
+
+	if len(r.frames) != 0 {
+		t.Fatalf("want nothing sent back, got %+v", r.frames)
+	}
+}
+
+func TestDispatchResetFailsStream(t *testing.T) {
+	session, _ := newTestSession()
+	stream := session.Open("a")
+
+	session.Dispatch(Frame{StreamID: "a", Type: borepb.FrameType_FRAME_RESET})
+
+	_, err := stream.Read(make([]byte, 1))
+	if !errors.Is(err, ErrStreamReset) {
+		t.Fatalf("want ErrStreamReset, got %v", err)
+	}
+	if session.Len() != 0 {
+		t.Fatalf("want the reset stream removed, %d open", session.Len())
+	}
+}
+
+func TestSessionResetFailsAllStreams(t *testing.T) {
+	session, _ := newTestSession()
+	a := session.Open("a")
+	b := session.Open("b")
+
+	session.Reset(ErrSessionReset)
+
+	for _, stream := range []*Stream{a, b} {
+		_, err := stream.Read(make([]byte, 1))
+		if !errors.Is(err, ErrSessionReset) {
+			t.Fatalf("stream %s: want ErrSessionReset, got %v", stream.ID(), err)
+		}
+	}
+	if session.Len() != 0 {
+		t.Fatalf("want no open streams, %d open", session.Len())
+	}
+}
+
+func TestFrameSurvivesProtoRoundTrip(t *testing.T) {
+	frame := Frame{
+		StreamID:    "a",
+		Type:        borepb.FrameType_FRAME_END,
+		MessageType: 2,
+		Data:        []byte("data"),
+		Window:      10,
+		Error:       "upstream refused connection",
+	}
+
+	got := FrameFromProto(frame.Proto())
+	if !reflect.DeepEqual(got, frame) {
+		t.Fatalf("want %+v, got %+v", frame, got)
+	}
+}
@@ -0,0 +1,307 @@
+package mux
+
+import (
+	borepb "bore/borepb"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Stream is one logical, bidirectional stream of a Session. Read and
+// ReadMessage consume DATA frames from the peer, Write and WriteMessage send
+// them, blocking while the peer's window is exhausted.
+type Stream struct {
+	id      string
+	session *Session
+
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	frames     []Frame
+	pending    []byte
+	consumed   int
+	received   int
+	recvWindow int
+	sendWindow int
+	remoteEnd  bool
+	remoteErr  error
+	err        error
+	endOnce    sync.Once
+}
+
+func newStream(id string, session *Session) *Stream {
+	stream := &Stream{
+		id:         id,
+		session:    session,
+		recvWindow: DefaultWindow,
+		sendWindow: DefaultWindow,
+	}
+	stream.cond = sync.NewCond(&stream.mutex)
+
+	return stream
+}
+
+func (s *Stream) ID() string {
+	return s.id
+}
+
+// Read returns io.EOF once the peer has sent END, or the error it ended the
+// stream with.
+func (s *Stream) Read(p []byte) (int, error) {
+	s.mutex.Lock()
+
+	for len(s.pending) == 0 {
+		if len(s.frames) > 0 {
+			s.pending = s.frames[0].Data
+			s.frames = s.frames[1:]
+			continue
+		}
+
+		if err := s.readErr(); err != nil {
+			s.mutex.Unlock()
+			return 0, err
+		}
+
+		s.cond.Wait()
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+
+	update := s.consume(n)
+	s.mutex.Unlock()
+
+	s.sendWindowUpdate(update)
+
+	return n, nil
+}
+
+// ReadMessage returns the next DATA frame whole, for websocket passthroughs
+// where message boundaries matter.
+func (s *Stream) ReadMessage() (int32, []byte, error) {
+	s.mutex.Lock()
+
+	for len(s.frames) == 0 {
+		if err := s.readErr(); err != nil {
+			s.mutex.Unlock()
+			return 0, nil, err
+		}
+
+		s.cond.Wait()
+	}
+
+	frame := s.frames[0]
+	s.frames = s.frames[1:]
+
+	update := s.consume(len(frame.Data))
+	s.mutex.Unlock()
+
+	s.sendWindowUpdate(update)
+
+	return frame.MessageType, frame.Data, nil
+}
+
+func (s *Stream) Write(p []byte) (int, error) {
+	written := 0
+
+	for written < len(p) {
+		n, err := s.reserve(min(len(p)-written, MaxFrameSize))
+		if err != nil {
+			return written, err
+		}
+
+		err = s.session.send(Frame{
+			StreamID: s.id,
+			Type:     borepb.FrameType_FRAME_DATA,
+			Data:     p[written : written+n],
+		})
+		if err != nil {
+			return written, err
+		}
+
+		written += n
+	}
+
+	return written, nil
+}
+
+// WriteMessage sends data as a single DATA frame. It waits for the window to
+// open but may overdraw it, since a websocket message can't be split.
+func (s *Stream) WriteMessage(messageType int32, data []byte) error {
+	s.mutex.Lock()
+	for s.sendWindow <= 0 && s.err == nil {
+		s.cond.Wait()
+	}
+	if s.err != nil {
+		err := s.err
+		s.mutex.Unlock()
+		return err
+	}
+	s.sendWindow -= len(data)
+	s.mutex.Unlock()
+
+	return s.session.send(Frame{
+		StreamID:    s.id,
+		Type:        borepb.FrameType_FRAME_DATA,
+		MessageType: messageType,
+		Data:        data,
+	})
+}
+
+// CloseWrite tells the peer nothing more will be written to the stream.
+func (s *Stream) CloseWrite() error {
+	return s.CloseWithError("")
+}
+
+// CloseWithError ends the write side of the stream, passing message to the
+// peer's reader as an error when it isn't empty.
+func (s *Stream) CloseWithError(message string) error {
+	var err error = ErrStreamClosed
+
+	s.endOnce.Do(func() {
+		err = s.session.send(Frame{
+			StreamID: s.id,
+			Type:     borepb.FrameType_FRAME_END,
+			Error:    message,
+		})
+	})
+
+	return err
+}
+
+// Close releases the stream, waking any blocked readers and writers. If the
+// peer is still part way through sending, it is told to stop with a RESET.
+func (s *Stream) Close() error {
+	s.mutex.Lock()
+	abandoned := s.received > 0 && !s.remoteEnd && s.err == nil
+	s.mutex.Unlock()
+
+	s.reset(ErrStreamClosed)
+	s.session.remove(s)
+
+	if abandoned {
+		return s.session.send(Frame{StreamID: s.id, Type: borepb.FrameType_FRAME_RESET})
+	}
+
+	return nil
+}
+
+func (s *Stream) readErr() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	if s.remoteEnd {
+		if s.remoteErr != nil {
+			return s.remoteErr
+		}
+		return io.EOF
+	}
+
+	return nil
+}
+
+// consume accounts for n bytes handed to the reader and returns the window
+// update to send, once at least half the window has been read. The update is
+// counted as granted right away, so DATA sent on it is never refused.
+func (s *Stream) consume(n int) int {
+	s.consumed += n
+	if s.consumed < DefaultWindow/2 || s.remoteEnd {
+		return 0
+	}
+
+	update := s.consumed
+	s.consumed = 0
+	s.recvWindow += update
+
+	return update
+}
+
+func (s *Stream) sendWindowUpdate(update int) {
+	if update == 0 {
+		return
+	}
+
+	s.session.send(Frame{
+		StreamID: s.id,
+		Type:     borepb.FrameType_FRAME_WINDOW,
+		Window:   uint32(update),
+	})
+}
+
+// reserve blocks until the peer has granted some window, and takes up to n
+// bytes of it.
+func (s *Stream) reserve(n int) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for s.sendWindow <= 0 && s.err == nil {
+		s.cond.Wait()
+	}
+
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	n = min(n, s.sendWindow)
+	s.sendWindow -= n
+
+	return n, nil
+}
+
+// deliver queues a DATA frame for the reader. A frame may overdraw what is
+// left of the window, as WriteMessage does, but once the window is used up
+// the stream fails with ErrWindowExceeded and deliver returns false, so a
+// misbehaving peer can't make it buffer without bound.
+func (s *Stream) deliver(frame Frame) bool {
+	s.mutex.Lock()
+
+	if s.remoteEnd || s.err != nil {
+		s.mutex.Unlock()
+		return true
+	}
+
+	if s.recvWindow <= 0 {
+		s.mutex.Unlock()
+		s.reset(ErrWindowExceeded)
+		return false
+	}
+
+	s.frames = append(s.frames, frame)
+	s.received += len(frame.Data)
+	s.recvWindow -= len(frame.Data)
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+
+	return true
+}
+
+func (s *Stream) end(message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.remoteEnd = true
+	if message != "" {
+		s.remoteErr = errors.New(message)
+	}
+	s.cond.Broadcast()
+}
+
+func (s *Stream) grant(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sendWindow += n
+	s.cond.Broadcast()
+}
+
+func (s *Stream) reset(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+	s.frames = nil
+	s.pending = nil
+	s.cond.Broadcast()
+}
@@ -0,0 +1,213 @@
+package mux
+
+import (
+	borepb "bore/borepb"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReadSendsWindowUpdateAfterHalfTheWindow(t *testing.T) {
+	session, r := newTestSession()
+	stream := session.Open("a")
+
+	for range DefaultWindow / MaxFrameSize {
+		session.Dispatch(dataFrame("a", MaxFrameSize))
+	}
+
+	buf := make([]byte, MaxFrameSize)
+	for range DefaultWindow/2/MaxFrameSize - 1 {
+		_, err := io.ReadFull(stream, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if updates := r.sent(borepb.FrameType_FRAME_WINDOW); len(updates) != 0 {
+		t.Fatalf("want no window update before half the window is read, got %+v", updates)
+	}
+
+	_, err := io.ReadFull(stream, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := r.sent(borepb.FrameType_FRAME_WINDOW)
+	if len(updates) != 1 || updates[0].Window != DefaultWindow/2 {
+		t.Fatalf("want one window update of %d, got %+v", DefaultWindow/2, updates)
+	}
+}
+
+func TestDataBeyondWindowResetsStream(t *testing.T) {
+	session, r := newTestSession()
+	stream := session.Open("a")
+
+	for range DefaultWindow / MaxFrameSize {
+		if !session.Dispatch(dataFrame("a", MaxFrameSize)) {
+			t.Fatal("stream closed before its window was used up")
+		}
+	}
+	if resets := r.sent(borepb.FrameType_FRAME_RESET); len(resets) != 0 {
+		t.Fatalf("want no RESET within the window, got %+v", resets)
+	}
+
+	session.Dispatch(dataFrame("a", 1))
+
+	resets := r.sent(borepb.FrameType_FRAME_RESET)
+	if len(resets) != 1 || resets[0].StreamID != "a" {
+		t.Fatalf("want one RESET for the stream, got %+v", resets)
+	}
+
+	_, err := stream.Read(make([]byte, 1))
+	if !errors.Is(err, ErrWindowExceeded) {
+		t.Fatalf("want ErrWindowExceeded, got %v", err)
+	}
+	if session.Len() != 0 {
+		t.Fatalf("want the stream removed, %d open", session.Len())
+	}
+}
+
+func TestDataWithinGrantedUpdateIsAccepted(t *testing.T) {
+	session, r := newTestSession()
+	stream := session.Open("a")
+
+	for range DefaultWindow / MaxFrameSize {
+		session.Dispatch(dataFrame("a", MaxFrameSize))
+	}
+
+	buf := make([]byte, DefaultWindow/2)
+	_, err := io.ReadFull(stream, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range DefaultWindow / 2 / MaxFrameSize {
+		if !session.Dispatch(dataFrame("a", MaxFrameSize)) {
+			t.Fatal("stream closed within the granted window")
+		}
+	}
+	if resets := r.sent(borepb.FrameType_FRAME_RESET); len(resets) != 0 {
+		t.Fatalf("want no RESET within the granted window, got %+v", resets)
+	}
+}
+
+func TestMessageMayOverdrawWindow(t *testing.T) {
+	session, r := newTestSession()
+	session.Open("a")
+
+	session.Dispatch(dataFrame("a", DefaultWindow-1))
+	session.Dispatch(dataFrame("a", MaxFrameSize))
+	if resets := r.sent(borepb.FrameType_FRAME_RESET); len(resets) != 0 {
+		t.Fatalf("want a message overdrawing the window accepted, got %+v", resets)
+	}
+
+	session.Dispatch(dataFrame("a", 1))
+	if resets := r.sent(borepb.FrameType_FRAME_RESET); len(resets) != 1 {
+		t.Fatalf("want a RESET once the window was overdrawn, got %+v", resets)
+	}
+}
+
+func TestWriteBlocksUntilWindowIsGranted(t *testing.T) {
+	session, r := newTestSession()
+	stream := session.Open("a")
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := stream.Write(make([]byte, DefaultWindow+10))
+		written <- err
+	}()
+
+	select {
+	case err := <-written:
+		t.Fatalf("Write returned with the window exhausted: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sent := 0
+	for _, frame := range r.sent(borepb.FrameType_FRAME_DATA) {
+		sent += len(frame.Data)
+	}
+	if sent != DefaultWindow {
+		t.Fatalf("want %d bytes sent before blocking, got %d", DefaultWindow, sent)
+	}
+
+	session.Dispatch(Frame{StreamID: "a", Type: borepb.FrameType_FRAME_WINDOW, Window: 10})
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write still blocked after the window was granted")
+	}
+}
+
+func TestResetWakesBlockedWriter(t *testing.T) {
+	session, _ := newTestSession()
+	stream := session.Open("a")
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := stream.Write(make([]byte, DefaultWindow+10))
+		written <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	session.Reset(ErrSessionReset)
+
+	select {
+	case err := <-written:
+		if !errors.Is(err, ErrSessionReset) {
+			t.Fatalf("want ErrSessionReset, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write still blocked after the session was reset")
+	}
+}
+
+func TestCloseWithUnreadDataSendsReset(t *testing.T) {
+	session, r := newTestSession()
+	stream := session.Open("a")
+
+	session.Dispatch(dataFrame("a", 10))
+	stream.Close()
+
+	resets := r.sent(borepb.FrameType_FRAME_RESET)
+	if len(resets) != 1 || resets[0].StreamID != "a" {
+		t.Fatalf("want one RESET for the abandoned stream, got %+v", resets)
+	}
+	if session.Len() != 0 {
+		t.Fatalf("want the stream removed, %d open", session.Len())
+	}
+}
+
+func TestCloseAfterEndSendsNoReset(t *testing.T) {
+	session, r := newTestSession()
+	stream := session.Open("a")
+
+	session.Dispatch(dataFrame("a", 10))
+	session.Dispatch(Frame{StreamID: "a", Type: borepb.FrameType_FRAME_END})
+
+	data, err := io.ReadAll(stream)
+	if err != nil || len(data) != 10 {
+		t.Fatalf("want 10 bytes and EOF, got %d bytes and %v", len(data), err)
+	}
+	stream.Close()
+
+	if resets := r.sent(borepb.FrameType_FRAME_RESET); len(resets) != 0 {
+		t.Fatalf("want no RESET for a finished stream, got %+v", resets)
+	}
+}
+
+func TestEndWithErrorIsReturnedByRead(t *testing.T) {
+	session, _ := newTestSession()
+	stream := session.Open("a")
+
+	session.Dispatch(Frame{StreamID: "a", Type: borepb.FrameType_FRAME_END, Error: "upstream refused connection"})
+
+	_, err := stream.Read(make([]byte, 1))
+	if err == nil || err.Error() != "upstream refused connection" {
+		t.Fatalf("want the peer's error, got %v", err)
+	}
+}
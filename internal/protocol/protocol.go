@@ -0,0 +1,52 @@
+// Package protocol holds the version and feature names bore clients and
+// servers agree on in the hello/welcome handshake.
+package protocol
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Version is bumped whenever the messages exchanged over the websocket
+// change incompatibly. MinVersion is the oldest version still understood.
+// Hello and Welcome are never wrapped in an envelope, so that peers of any
+// version can always tell each other to upgrade.
+const (
+	Version    uint32 = 3
+	MinVersion uint32 = 3
+)
+
+// Header carries the client's protocol version on the websocket upgrade
+// request, so the server can turn away clients from before the hello/welcome
+// handshake without upgrading them first.
+const Header = "X-Bore-Protocol"
+
+// ErrorShuttingDown is the error a client answers new requests with once it
+// is shutting down, so the server can tell visitors the tunnel is going away
+// rather than blame the upstream.
+const ErrorShuttingDown = "shutting down"
+
+// HandshakeTimeout bounds the wait for the peer's hello or welcome.
+const HandshakeTimeout = 10 * time.Second
+
+const (
+	FeatureTCP       = "tcp"
+	FeatureWebSocket = "websocket"
+	FeatureResume    = "resume"
+)
+
+// Negotiate picks the version to speak with a peer that speaks up to
+// peerVersion, or returns an error if the two have nothing in common.
+func Negotiate(peerVersion uint32) (uint32, error) {
+	version := min(peerVersion, Version)
+	if version < MinVersion {
+		return 0, fmt.Errorf("protocol v%d is no longer supported, v%d or newer is required", peerVersion, MinVersion)
+	}
+
+	return version, nil
+}
+
+func Supports(features []string, feature string) bool {
+	return slices.Contains(features, feature)
+}
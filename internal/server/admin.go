@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+type appInfo struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url,omitempty"`
+	TCPPort       int       `json:"tcp_port,omitempty"`
+	Connected     bool      `json:"connected"`
+	ClientIP      string    `json:"client_ip"`
+	ClientVersion string    `json:"client_version"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	CustomDomain  string    `json:"custom_domain,omitempty"`
+	Token         string    `json:"token,omitempty"`
+	Requests      int64     `json:"requests"`
+	OpenStreams   int       `json:"open_streams"`
+	ClientStats   *appStats `json:"client_stats,omitempty"`
+}
+
+// appStats is what the bore client last reported about itself.
+type appStats struct {
+	OpenStreams   int64     `json:"open_streams"`
+	RequestsTotal int64     `json:"requests_total"`
+	ReportedAt    time.Time `json:"reported_at"`
+}
+
+func (app *App) info() appInfo {
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	info := appInfo{
+		ID:            app.id,
+		URL:           app.publicURL,
+		TCPPort:       app.tcpPort,
+		Connected:     app.wsConn != nil,
+		CustomDomain:  app.customDomain,
+		ClientIP:      app.clientIP,
+		ClientVersion: app.clientVersion,
+		ConnectedAt:   app.connectedAt,
+		Requests:      app.requests.Load(),
+		OpenStreams:   app.streams.Len(),
+	}
+	if app.token != nil {
+		info.Token = app.token.name
+	}
+	if app.stats != nil {
+		info.ClientStats = &appStats{
+			OpenStreams:   app.stats.OpenStreams,
+			RequestsTotal: app.stats.RequestsTotal,
+			ReportedAt:    time.UnixMilli(app.stats.Timestamp),
+		}
+	}
+
+	return info
+}
+
+// startAdminServer serves operator endpoints on their own address, so they
+// aren't reachable through the public tunnel port.
+func (bs *BoreServer) startAdminServer() error {
+	router := chi.NewRouter()
+	router.Handle("/metrics", promhttp.HandlerFor(bs.metrics.registry, promhttp.HandlerOpts{}))
+
+	if bs.adminToken != "" {
+		router.Route("/api", func(r chi.Router) {
+			r.Use(bs.requireAdminToken)
+
+			r.Get("/apps", bs.listApps)
+			r.Get("/apps/{appID}", bs.getApp)
+			r.Delete("/apps/{appID}", bs.deleteApp)
+			r.Get("/subdomains/reserved", bs.listReservedSubdomains)
+			r.Put("/subdomains/reserved/{subdomain}", bs.reserveSubdomain)
+			r.Delete("/subdomains/reserved/{subdomain}", bs.releaseSubdomain)
+			r.Get("/config", bs.getConfig)
+		})
+	} else {
+		bs.logger.Warn("admin api is disabled, set an admin token to enable it")
+	}
+
+	bs.logger.Info("admin server is running", zap.String("admin_addr", bs.adminAddr))
+	return http.ListenAndServe(bs.adminAddr, router)
+}
+
+func (bs *BoreServer) requireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || !secureCompare(token, bs.adminToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bore admin"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "admin token required"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (bs *BoreServer) listApps(w http.ResponseWriter, r *http.Request) {
+	apps := []appInfo{}
+	bs.apps.Range(func(_ string, app *App) bool {
+		apps = append(apps, app.info())
+		return true
+	})
+
+	slices.SortFunc(apps, func(a, b appInfo) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "apps": apps})
+}
+
+func (bs *BoreServer) getApp(w http.ResponseWriter, r *http.Request) {
+	app, ok := bs.apps.Lookup(chi.URLParam(r, "appID"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "app not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "app": app.info()})
+}
+
+// deleteApp force-disconnects an app. Its client is told not to reconnect.
+func (bs *BoreServer) deleteApp(w http.ResponseWriter, r *http.Request) {
+	app, ok := bs.apps.Lookup(chi.URLParam(r, "appID"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "app not found"})
+		return
+	}
+
+	bs.disconnectApp(app, "disconnected by the bore server operator")
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "app": app.info()})
+}
+
+func (bs *BoreServer) listReservedSubdomains(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "subdomains": bs.reservedSubdomainList()})
+}
+
+func (bs *BoreServer) reserveSubdomain(w http.ResponseWriter, r *http.Request) {
+	subdomain := strings.ToLower(chi.URLParam(r, "subdomain"))
+	if !subdomainPattern.MatchString(subdomain) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("subdomain %q is not valid", subdomain)})
+		return
+	}
+
+	bs.reservedSubdomains.Register(subdomain, struct{}{})
+	bs.logger.Info("reserved subdomain", zap.String("subdomain", subdomain))
+
+	// reserving doesn't affect an app that is already using the subdomain
+	_, inUse := bs.apps.Lookup(subdomain)
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "subdomain": subdomain, "in_use": inUse})
+}
+
+func (bs *BoreServer) releaseSubdomain(w http.ResponseWriter, r *http.Request) {
+	subdomain := strings.ToLower(chi.URLParam(r, "subdomain"))
+
+	if subdomain == bs.hostLabel() {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": fmt.Sprintf("subdomain %q is the bore server's own host", subdomain)})
+		return
+	}
+
+	_, ok := bs.reservedSubdomains.Unregister(subdomain)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": fmt.Sprintf("subdomain %q is not reserved", subdomain)})
+		return
+	}
+
+	bs.logger.Info("released subdomain", zap.String("subdomain", subdomain))
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "subdomain": subdomain})
+}
+
+func (bs *BoreServer) getConfig(w http.ResponseWriter, r *http.Request) {
+	config := map[string]any{
+		"version":             bs.version,
+		"port":                bs.port,
+		"resume_grace":        bs.resumeGracePeriod.String(),
+		"request_timeout":     bs.requestTimeout.String(),
+		"tcp_port_min":        bs.tcpPortMin,
+		"tcp_port_max":        bs.tcpPortMax,
+		"rate_limit":          bs.rateLimit,
+		"rate_burst":          bs.rateBurst,
+		"max_body_size":       bs.maxBodySize,
+		"max_in_flight":       bs.maxInFlight,
+		"reserved_subdomains": bs.reservedSubdomainList(),
+		"tokens":              bs.tokens.list(),
+		"domains":             bs.baseDomainList(),
+		"host":                bs.host,
+		"scheme":              bs.scheme,
+	}
+	if bs.visitorLimiter != nil {
+		config["visitor_rate_limit"] = bs.visitorLimiter.rate
+		config["visitor_rate_burst"] = bs.visitorLimiter.burst
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "config": config})
+}
+
+func (bs *BoreServer) reservedSubdomainList() []string {
+	subdomains := []string{}
+	bs.reservedSubdomains.Range(func(subdomain string, _ struct{}) bool {
+		subdomains = append(subdomains, subdomain)
+		return true
+	})
+	slices.Sort(subdomains)
+
+	return subdomains
+}
+
+// disconnectApp closes an app's connection and unregisters it right away.
+// The close code tells the client it was kicked, so it doesn't reconnect.
+func (bs *BoreServer) disconnectApp(app *App, reason string) {
+	conn := app.conn()
+	if conn != nil {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason), time.Now().Add(5*time.Second))
+	}
+
+	bs.releaseApp(app, conn)
+	if conn != nil {
+		conn.Close()
+	}
+
+	bs.logger.Info("disconnected app", zap.String("app_id", app.id), zap.String("reason", reason))
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TokenConfig describes an API token. Only the SHA-256 of the token is kept,
+// so a leaked tokens file can't be used to open tunnels.
+type TokenConfig struct {
+	Name       string   `json:"name"`
+	SHA256     string   `json:"sha256"`
+	MaxTunnels int      `json:"max_tunnels"`
+	Subdomains []string `json:"subdomains"`
+}
+
+type token struct {
+	name       string
+	maxTunnels int
+	tunnels    int
+}
+
+// tokenStore authenticates clients and tracks how many tunnels each token
+// has open.
+type tokenStore struct {
+	mutex      sync.Mutex
+	byHash     map[string]*token
+	subdomains map[string]*token
+}
+
+var errTokenRequired = errors.New("this bore server requires a token, run `bore login <token>` to store one")
+var errInvalidToken = errors.New("bore token is not valid, run `bore login <token>` to store a new one")
+
+// LoadTokens reads a JSON array of TokenConfig from path.
+func LoadTokens(path string) ([]TokenConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []TokenConfig
+	err = json.Unmarshal(data, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	owners := make(map[string]string)
+	for _, config := range tokens {
+		if len(config.SHA256) != sha256.Size*2 {
+			return nil, fmt.Errorf("token %q: sha256 must be %d hex characters", config.Name, sha256.Size*2)
+		}
+
+		// clients lowercase the subdomains they ask for
+		for i, subdomain := range config.Subdomains {
+			subdomain = strings.ToLower(strings.TrimSpace(subdomain))
+			if !subdomainPattern.MatchString(subdomain) {
+				return nil, fmt.Errorf("token %q: subdomain %q is not a valid DNS label", config.Name, config.Subdomains[i])
+			}
+			config.Subdomains[i] = subdomain
+
+			if owner, ok := owners[subdomain]; ok {
+				return nil, fmt.Errorf("subdomain %q is reserved by both %q and %q", subdomain, owner, config.Name)
+			}
+			owners[subdomain] = config.Name
+		}
+	}
+
+	return tokens, nil
+}
+
+// GenerateToken returns a new random token and the hash to store for it.
+func GenerateToken() (string, string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	token := "bore_" + base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTokenStore(configs []TokenConfig) *tokenStore {
+	store := &tokenStore{
+		byHash:     make(map[string]*token),
+		subdomains: make(map[string]*token),
+	}
+
+	for _, config := range configs {
+		tok := &token{
+			name:       config.Name,
+			maxTunnels: config.MaxTunnels,
+		}
+		store.byHash[strings.ToLower(config.SHA256)] = tok
+
+		for _, subdomain := range config.Subdomains {
+			store.subdomains[subdomain] = tok
+		}
+	}
+
+	return store
+}
+
+// enabled reports whether clients need a token to connect.
+func (ts *tokenStore) enabled() bool {
+	return len(ts.byHash) > 0
+}
+
+func (ts *tokenStore) authenticate(secret string) (*token, error) {
+	if !ts.enabled() {
+		return nil, nil
+	}
+
+	if secret == "" {
+		return nil, errTokenRequired
+	}
+
+	tok, ok := ts.byHash[hashToken(secret)]
+	if !ok {
+		return nil, errInvalidToken
+	}
+
+	return tok, nil
+}
+
+// canClaim reports whether tok may use subdomain, i.e. it isn't reserved for
+// another token.
+func (ts *tokenStore) canClaim(tok *token, subdomain string) bool {
+	owner, ok := ts.subdomains[subdomain]
+	return !ok || owner == tok
+}
+
+// acquire counts a new tunnel against tok's limit.
+func (ts *tokenStore) acquire(tok *token) error {
+	if tok == nil {
+		return nil
+	}
+
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if tok.maxTunnels > 0 && tok.tunnels >= tok.maxTunnels {
+		return fmt.Errorf("bore token %q already has %d of %d tunnels open", tok.name, tok.tunnels, tok.maxTunnels)
+	}
+
+	tok.tunnels++
+	return nil
+}
+
+type tokenInfo struct {
+	Name       string   `json:"name"`
+	MaxTunnels int      `json:"max_tunnels"`
+	Tunnels    int      `json:"tunnels"`
+	Subdomains []string `json:"subdomains"`
+}
+
+// list describes the configured tokens without their hashes.
+func (ts *tokenStore) list() []tokenInfo {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	tokens := []tokenInfo{}
+	for _, tok := range ts.byHash {
+		info := tokenInfo{
+			Name:       tok.name,
+			MaxTunnels: tok.maxTunnels,
+			Tunnels:    tok.tunnels,
+			Subdomains: []string{},
+		}
+		for subdomain, owner := range ts.subdomains {
+			if owner == tok {
+				info.Subdomains = append(info.Subdomains, subdomain)
+			}
+		}
+		slices.Sort(info.Subdomains)
+
+		tokens = append(tokens, info)
+	}
+
+	slices.SortFunc(tokens, func(a, b tokenInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return tokens
+}
+
+func (ts *tokenStore) release(tok *token) {
+	if tok == nil {
+		return
+	}
+
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	tok.tunnels--
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tokenConfig configures a token with the given secret.
+func tokenConfig(name string, secret string) TokenConfig {
+	return TokenConfig{Name: name, SHA256: hashToken(secret)}
+}
+
+func TestLoadTokens(t *testing.T) {
+	hash := hashToken("secret")
+
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+		want    []string
+	}{
+		{
+			name: "valid",
+			json: `[{"name": "alice", "sha256": "` + hash + `", "subdomains": [" Alice-App "]}]`,
+			want: []string{"alice-app"},
+		},
+		{
+			name:    "short hash",
+			json:    `[{"name": "alice", "sha256": "abc"}]`,
+			wantErr: "sha256 must be 64 hex characters",
+		},
+		{
+			name:    "invalid subdomain",
+			json:    `[{"name": "alice", "sha256": "` + hash + `", "subdomains": ["-bad"]}]`,
+			wantErr: `subdomain "-bad" is not a valid DNS label`,
+		},
+		{
+			name:    "subdomain reserved twice",
+			json:    `[{"name": "alice", "sha256": "` + hash + `", "subdomains": ["shop"]}, {"name": "bob", "sha256": "` + hash + `", "subdomains": ["SHOP"]}]`,
+			wantErr: `subdomain "shop" is reserved by both "alice" and "bob"`,
+		},
+		{
+			name:    "not json",
+			json:    `{`,
+			wantErr: "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens.json")
+			err := os.WriteFile(path, []byte(tt.json), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokens, err := LoadTokens(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tokens) != 1 || strings.Join(tokens[0].Subdomains, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("want subdomains %v, got %+v", tt.want, tokens)
+			}
+		})
+	}
+}
+
+func TestTokenStoreAuthenticate(t *testing.T) {
+	store := newTokenStore([]TokenConfig{tokenConfig("alice", "alice-secret")})
+
+	tests := []struct {
+		name    string
+		secret  string
+		want    string
+		wantErr error
+	}{
+		{"valid token", "alice-secret", "alice", nil},
+		{"missing token", "", "", errTokenRequired},
+		{"unknown token", "mallory-secret", "", errInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, err := store.authenticate(tt.secret)
+			if err != tt.wantErr {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if tt.want != "" && (tok == nil || tok.name != tt.want) {
+				t.Fatalf("want token %q, got %+v", tt.want, tok)
+			}
+		})
+	}
+
+	open := newTokenStore(nil)
+	if tok, err := open.authenticate("anything"); tok != nil || err != nil {
+		t.Fatalf("want no token required without tokens, got %+v, %v", tok, err)
+	}
+}
+
+func TestTokenStoreCanClaim(t *testing.T) {
+	alice := tokenConfig("alice", "alice-secret")
+	alice.Subdomains = []string{"shop"}
+	store := newTokenStore([]TokenConfig{alice, tokenConfig("bob", "bob-secret")})
+	aliceToken, _ := store.authenticate("alice-secret")
+	bobToken, _ := store.authenticate("bob-secret")
+
+	tests := []struct {
+		name      string
+		tok       *token
+		subdomain string
+		want      bool
+	}{
+		{"owner claims its subdomain", aliceToken, "shop", true},
+		{"other token claims it", bobToken, "shop", false},
+		{"no token claims it", nil, "shop", false},
+		{"unreserved subdomain", bobToken, "blog", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.canClaim(tt.tok, tt.subdomain); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTokenStoreAcquire(t *testing.T) {
+	limited := tokenConfig("alice", "alice-secret")
+	limited.MaxTunnels = 2
+	store := newTokenStore([]TokenConfig{limited, tokenConfig("bob", "bob-secret")})
+	alice, _ := store.authenticate("alice-secret")
+	bob, _ := store.authenticate("bob-secret")
+
+	for range 2 {
+		if err := store.acquire(alice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.acquire(alice); err == nil {
+		t.Fatal("want the third tunnel refused")
+	}
+
+	store.release(alice)
+	if err := store.acquire(alice); err != nil {
+		t.Fatalf("want a released tunnel to free up room, got %v", err)
+	}
+
+	for range 10 {
+		if err := store.acquire(bob); err != nil {
+			t.Fatalf("want no limit for tokens without max_tunnels, got %v", err)
+		}
+	}
+	if err := store.acquire(nil); err != nil {
+		t.Fatalf("want no limit without tokens, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	domainChallengeRecord  = "_bore-challenge"
+	domainChallengeTimeout = 10 * time.Second
+	minDomainKeyLength     = 32
+)
+
+var hostnamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// normalizeDomain lowercases a hostname and strips a port or trailing dot,
+// so it can be compared with request Host headers.
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+
+	return strings.TrimSuffix(domain, ".")
+}
+
+// domainChallenge is the TXT record value that proves the holder of key
+// controls domain. It only changes with the key, so it can be set up ahead
+// of time, and it doesn't reveal the key to anyone reading DNS.
+func domainChallenge(domain string, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(domain))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyDomain checks that whoever asked for domain controls its DNS, with a
+// TXT record derived from their domain key. Merely pointing the domain at
+// this server isn't enough, or anyone could claim it while its owner is
+// offline.
+func (bs *BoreServer) verifyDomain(domain string, key string) error {
+	if !hostnamePattern.MatchString(domain) {
+		return fmt.Errorf("custom domain %q is not a valid hostname", domain)
+	}
+
+	if bs.isBaseDomain(domain) {
+		return fmt.Errorf("custom domain %q is part of the bore domain, use a subdomain instead", domain)
+	}
+
+	if _, inUse := bs.customDomains.Lookup(domain); inUse {
+		return fmt.Errorf("custom domain %q is already in use", domain)
+	}
+
+	if len(key) < minDomainKeyLength {
+		return fmt.Errorf("custom domain %q needs a domain key, please upgrade bore", domain)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), domainChallengeTimeout)
+	defer cancel()
+
+	challenge := domainChallenge(domain, key)
+	logger := bs.logger.With(zap.String("custom_domain", domain))
+
+	err := bs.verifyDomainTXT(ctx, domain, challenge)
+	if err != nil {
+		logger.Debug("txt challenge failed", zap.Error(err))
+		return fmt.Errorf("could not verify custom domain %q: add a TXT record %s.%s with the value %s", domain, domainChallengeRecord, domain, challenge)
+	}
+
+	logger.Info("verified custom domain")
+	return nil
+}
+
+func (bs *BoreServer) verifyDomainTXT(ctx context.Context, domain string, challenge string) error {
+	records, err := net.DefaultResolver.LookupTXT(ctx, domainChallengeRecord+"."+domain)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == challenge {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no matching record among %d found", len(records))
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// hasEdgeAuth reports whether visitors must authenticate before requests are
+// forwarded to the bore client.
+func (app *App) hasEdgeAuth() bool {
+	return app.basicAuth != "" || app.bearerToken != ""
+}
+
+// authorize checks the visitor's Authorization header against the
+// credentials the app was registered with. Either scheme is accepted when
+// both are configured.
+func (app *App) authorize(r *http.Request) bool {
+	if !app.hasEdgeAuth() {
+		return true
+	}
+
+	if app.basicAuth != "" {
+		user, pass, ok := r.BasicAuth()
+		if ok && secureCompare(user+":"+pass, app.basicAuth) {
+			return true
+		}
+	}
+
+	if app.bearerToken != "" {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, "Bearer") && secureCompare(token, app.bearerToken) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (app *App) challenge(w http.ResponseWriter) {
+	if app.basicAuth != "" {
+		w.Header().Add("WWW-Authenticate", `Basic realm="bore", charset="UTF-8"`)
+	}
+	if app.bearerToken != "" {
+		w.Header().Add("WWW-Authenticate", `Bearer realm="bore"`)
+	}
+
+	renderErrorPage(w, http.StatusUnauthorized, "This tunnel is protected. Please sign in to continue.")
+}
+
+func secureCompare(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name          string
+		basicAuth     string
+		bearerToken   string
+		authorization string
+		want          bool
+	}{
+		{"no edge auth", "", "", "", true},
+		{"basic auth", "alice:secret", "", "Basic YWxpY2U6c2VjcmV0", true},
+		{"wrong password", "alice:secret", "", "Basic YWxpY2U6d3Jvbmc=", false},
+		{"missing credentials", "alice:secret", "", "", false},
+		{"password with a colon", "alice:se:cret", "", "Basic YWxpY2U6c2U6Y3JldA==", true},
+		{"bearer token", "", "tok3n", "Bearer tok3n", true},
+		{"bearer scheme is case insensitive", "", "tok3n", "bearer tok3n", true},
+		{"wrong bearer token", "", "tok3n", "Bearer other", false},
+		{"bearer token as basic auth", "", "tok3n", "Basic dG9rM246", false},
+		{"either scheme, basic", "alice:secret", "tok3n", "Basic YWxpY2U6c2VjcmV0", true},
+		{"either scheme, bearer", "alice:secret", "tok3n", "Bearer tok3n", true},
+		{"either scheme, neither", "alice:secret", "tok3n", "Bearer secret", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{basicAuth: tt.basicAuth, bearerToken: tt.bearerToken}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			if got := app.authorize(r); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	tests := []struct {
+		name        string
+		basicAuth   string
+		bearerToken string
+		want        []string
+	}{
+		{"basic auth", "alice:secret", "", []string{`Basic realm="bore", charset="UTF-8"`}},
+		{"bearer token", "", "tok3n", []string{`Bearer realm="bore"`}},
+		{"both", "alice:secret", "tok3n", []string{`Basic realm="bore", charset="UTF-8"`, `Bearer realm="bore"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{basicAuth: tt.basicAuth, bearerToken: tt.bearerToken}
+			w := httptest.NewRecorder()
+			app.challenge(w)
+
+			got := w.Header().Values("WWW-Authenticate")
+			if w.Code != http.StatusUnauthorized || len(got) != len(tt.want) {
+				t.Fatalf("want 401 with %q, got %d with %q", tt.want, w.Code, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("want %q, got %q", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	borepb "bore/borepb"
+	"bore/internal/protocol"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkProtocolHeader rejects clients that don't speak a protocol version
+// the server understands, before their connection is upgraded. Clients from
+// before the handshake don't send the header at all.
+func checkProtocolHeader(r *http.Request) error {
+	header := r.Header.Get(protocol.Header)
+	if header == "" {
+		return errors.New("This bore client is out of date. Please upgrade bore.")
+	}
+
+	version, err := strconv.ParseUint(header, 10, 32)
+	if err != nil {
+		return fmt.Errorf("This bore client sent an invalid protocol version %q. Please upgrade bore.", header)
+	}
+
+	_, err = protocol.Negotiate(uint32(version))
+	if err != nil {
+		return fmt.Errorf("This bore client is out of date: %s. Please upgrade bore.", err)
+	}
+
+	return nil
+}
+
+func (bs *BoreServer) readHello(conn *websocket.Conn) (*borepb.Hello, error) {
+	conn.SetReadDeadline(time.Now().Add(protocol.HandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	hello := &borepb.Hello{}
+	err = proto.Unmarshal(message, hello)
+	if err != nil {
+		return nil, err
+	}
+
+	return hello, nil
+}
+
+func (bs *BoreServer) writeWelcome(conn *websocket.Conn, welcome *borepb.Welcome) error {
+	welcome.ServerVersion = bs.version
+
+	message, err := proto.Marshal(welcome)
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteMessage(websocket.BinaryMessage, message)
+}
+
+// rejectClient tells the client why it can't connect and closes the
+// connection. Clients give up instead of reconnecting on a rejection.
+func (bs *BoreServer) rejectClient(conn *websocket.Conn, message string) {
+	defer conn.Close()
+
+	bs.writeWelcome(conn, &borepb.Welcome{
+		ProtocolVersion: protocol.Version,
+		Error:           message,
+	})
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(5*time.Second))
+}
+
+func (bs *BoreServer) capabilities() []string {
+	capabilities := []string{protocol.FeatureWebSocket, protocol.FeatureResume}
+	if bs.tcpPortMin != 0 {
+		capabilities = append(capabilities, protocol.FeatureTCP)
+	}
+
+	return capabilities
+}
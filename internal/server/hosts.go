@@ -0,0 +1,170 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// baseDomain is a public domain the server answers on, with apps on its
+// subdomains. addr keeps the port, if any, for building public URLs.
+type baseDomain struct {
+	host string
+	addr string
+}
+
+// parseBaseDomains sorts the domains longest first, so nested domains like
+// tunnels.example.com win over example.com when matching hosts.
+func parseBaseDomains(domains []string) []baseDomain {
+	baseDomains := []baseDomain{}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+
+		baseDomains = append(baseDomains, baseDomain{
+			host: normalizeDomain(domain),
+			addr: strings.TrimSuffix(domain, "."),
+		})
+	}
+
+	slices.SortStableFunc(baseDomains, func(a, b baseDomain) int {
+		return cmp.Compare(len(b.host), len(a.host))
+	})
+
+	return baseDomains
+}
+
+// matchBaseDomain finds the base domain host belongs to, and the subdomain
+// label in front of it. The label is empty for the base domain itself.
+func (bs *BoreServer) matchBaseDomain(host string) (baseDomain, string, bool) {
+	host = normalizeDomain(host)
+
+	for _, domain := range bs.baseDomains {
+		if host == domain.host {
+			return domain, "", true
+		}
+
+		label, ok := strings.CutSuffix(host, "."+domain.host)
+		if ok {
+			return domain, label, true
+		}
+	}
+
+	return baseDomain{}, "", false
+}
+
+// appIdForHost finds the app a request is for, by its custom domain or its
+// subdomain of a base domain. ok is false for hosts that aren't an app's,
+// like the base domains themselves.
+func (bs *BoreServer) appIdForHost(host string) (appId string, ok bool) {
+	if appId, ok := bs.customDomains.Lookup(normalizeDomain(host)); ok {
+		return appId, true
+	}
+
+	// without configured domains, the first label is taken as the app ID
+	if len(bs.baseDomains) == 0 {
+		return strings.Split(host, ".")[0], false
+	}
+
+	_, label, ok := bs.matchBaseDomain(host)
+	if !ok || label == "" || strings.Contains(label, ".") {
+		return "", false
+	}
+
+	return label, true
+}
+
+// isServerHost reports whether host is the bore server's own, where /ws is
+// the bore client endpoint: its --host, or a bare base domain. Without
+// --host the server can't tell its own host from an app's, so /ws is the
+// client endpoint everywhere.
+func (bs *BoreServer) isServerHost(host string) bool {
+	if bs.host == "" {
+		return true
+	}
+
+	host = normalizeDomain(host)
+	if host == bs.host {
+		return true
+	}
+
+	_, label, ok := bs.matchBaseDomain(host)
+	return ok && label == ""
+}
+
+// hostLabel is the app ID the server's own host would map to, which is never
+// handed out, so no app can be reached on the server's host.
+func (bs *BoreServer) hostLabel() string {
+	if bs.host == "" {
+		return ""
+	}
+
+	label, _ := bs.appIdForHost(bs.host)
+	return label
+}
+
+// parentDomain drops the first label of host, e.g. app.trybore.com:8080
+// becomes trybore.com:8080. Hosts with two labels or less, and IPs, are kept
+// whole.
+func parentDomain(host string) string {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if net.ParseIP(hostname) != nil {
+		return host
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return host
+	}
+
+	return strings.Join(labels[1:], ".")
+}
+
+func (bs *BoreServer) baseDomainList() []string {
+	domains := []string{}
+	for _, domain := range bs.baseDomains {
+		domains = append(domains, domain.addr)
+	}
+
+	return domains
+}
+
+// isBaseDomain reports whether domain is one of the base domains or under one.
+func (bs *BoreServer) isBaseDomain(domain string) bool {
+	_, _, ok := bs.matchBaseDomain(domain)
+	return ok
+}
+
+// publicURL is the URL visitors reach app on: its custom domain, or a
+// subdomain of the base domain the client connected through. Without
+// configured domains, apps are siblings of the host the client connected to.
+func (bs *BoreServer) publicURL(app *App, clientHost string) string {
+	if app.customDomain != "" && app.tcpListener == nil {
+		return fmt.Sprintf("%s://%s", bs.scheme, app.customDomain)
+	}
+
+	domain, _, ok := bs.matchBaseDomain(clientHost)
+	if !ok {
+		domains := bs.baseDomains
+		if len(domains) == 0 {
+			domains = parseBaseDomains([]string{parentDomain(clientHost)})
+		}
+		if len(domains) == 0 {
+			return ""
+		}
+		domain = domains[0]
+	}
+
+	if app.tcpListener != nil {
+		return fmt.Sprintf("tcp://%s", net.JoinHostPort(domain.host, fmt.Sprint(app.tcpPort)))
+	}
+
+	return fmt.Sprintf("%s://%s.%s", bs.scheme, app.id, domain.addr)
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"net"
+	"testing"
+)
+
+func TestMatchBaseDomain(t *testing.T) {
+	bs := newTestServer(t, &BoreServerCfg{Domains: []string{"example.com", "Tunnels.Example.com.", "bore.test:5001"}})
+
+	tests := []struct {
+		host   string
+		domain string
+		label  string
+		ok     bool
+	}{
+		{"example.com", "example.com", "", true},
+		{"app.example.com", "example.com", "app", true},
+		{"APP.Example.COM.", "example.com", "app", true},
+		{"app.example.com:8080", "example.com", "app", true},
+		{"app.tunnels.example.com", "tunnels.example.com", "app", true},
+		{"tunnels.example.com", "tunnels.example.com", "", true},
+		{"a.b.example.com", "example.com", "a.b", true},
+		{"app.bore.test:5001", "bore.test", "app", true},
+		{"notexample.com", "", "", false},
+		{"example.org", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			domain, label, ok := bs.matchBaseDomain(tt.host)
+			if domain.host != tt.domain || label != tt.label || ok != tt.ok {
+				t.Fatalf("want (%q, %q, %v), got (%q, %q, %v)", tt.domain, tt.label, tt.ok, domain.host, label, ok)
+			}
+		})
+	}
+}
+
+func TestAppIdForHost(t *testing.T) {
+	bs := newTestServer(t, &BoreServerCfg{Domains: []string{"example.com"}})
+	bs.customDomains.Register("shop.example.org", "happy-app")
+
+	unconfigured := newTestServer(t, &BoreServerCfg{})
+
+	tests := []struct {
+		name  string
+		bs    *BoreServer
+		host  string
+		appId string
+		ok    bool
+	}{
+		{"subdomain", bs, "happy-app.example.com", "happy-app", true},
+		{"subdomain with port", bs, "happy-app.example.com:443", "happy-app", true},
+		{"custom domain", bs, "Shop.Example.org", "happy-app", true},
+		{"base domain", bs, "example.com", "", false},
+		{"nested subdomain", bs, "a.happy-app.example.com", "", false},
+		{"unknown domain", bs, "happy-app.example.net", "", false},
+		{"without domains", unconfigured, "happy-app.localhost", "happy-app", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appId, ok := tt.bs.appIdForHost(tt.host)
+			if appId != tt.appId || ok != tt.ok {
+				t.Fatalf("want (%q, %v), got (%q, %v)", tt.appId, tt.ok, appId, ok)
+			}
+		})
+	}
+}
+
+func TestIsServerHost(t *testing.T) {
+	withHost := newTestServer(t, &BoreServerCfg{Domains: []string{"trybore.com"}, Host: "app.trybore.com"})
+	withIPHost := newTestServer(t, &BoreServerCfg{Domains: []string{"trybore.com"}, Host: "127.0.0.1:8080"})
+	withoutHost := newTestServer(t, &BoreServerCfg{Domains: []string{"trybore.com"}})
+
+	tests := []struct {
+		name string
+		bs   *BoreServer
+		host string
+		want bool
+	}{
+		{"server host", withHost, "app.trybore.com", true},
+		{"server host with port", withHost, "APP.trybore.com:443", true},
+		{"base domain", withHost, "trybore.com", true},
+		{"app host", withHost, "happy-app.trybore.com", false},
+		{"ip host", withIPHost, "127.0.0.1:8080", true},
+		{"app host next to an ip host", withIPHost, "happy-app.trybore.com", false},
+		{"without --host", withoutHost, "happy-app.trybore.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.bs.isServerHost(tt.host); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHostLabelIsReserved(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   *BoreServerCfg
+		label string
+	}{
+		{"subdomain host", &BoreServerCfg{Domains: []string{"trybore.com"}, Host: "bore.trybore.com"}, "bore"},
+		{"base domain host", &BoreServerCfg{Domains: []string{"trybore.com"}, Host: "trybore.com"}, ""},
+		{"ip host", &BoreServerCfg{Domains: []string{"trybore.com"}, Host: "127.0.0.1"}, ""},
+		{"without --host", &BoreServerCfg{Domains: []string{"trybore.com"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := newTestServer(t, tt.cfg)
+			if got := bs.hostLabel(); got != tt.label {
+				t.Fatalf("want label %q, got %q", tt.label, got)
+			}
+			if tt.label == "" {
+				return
+			}
+			if _, reserved := bs.reservedSubdomains.Lookup(tt.label); !reserved {
+				t.Fatalf("want %q reserved", tt.label)
+			}
+		})
+	}
+}
+
+func TestParentDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"app.trybore.com", "trybore.com"},
+		{"app.trybore.com:8080", "trybore.com:8080"},
+		{"a.b.trybore.com", "b.trybore.com"},
+		{"trybore.com", "trybore.com"},
+		{"localhost:8080", "localhost:8080"},
+		{"127.0.0.1:8080", "127.0.0.1:8080"},
+		{"[::1]:8080", "[::1]:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := parentDomain(tt.host); got != tt.want {
+				t.Fatalf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPublicURL(t *testing.T) {
+	configured := newTestServer(t, &BoreServerCfg{Domains: []string{"example.com", "bore.test:5001"}})
+	unconfigured := newTestServer(t, &BoreServerCfg{})
+	plainHTTP := newTestServer(t, &BoreServerCfg{Scheme: "http"})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	tests := []struct {
+		name       string
+		bs         *BoreServer
+		app        *App
+		clientHost string
+		want       string
+	}{
+		{"connected through a base domain", configured, &App{id: "happy-app"}, "bore.test:5001", "https://happy-app.bore.test:5001"},
+		{"connected through a subdomain", configured, &App{id: "happy-app"}, "app.example.com", "https://happy-app.example.com"},
+		{"connected through another host", configured, &App{id: "happy-app"}, "10.0.0.1", "https://happy-app.example.com"},
+		{"custom domain", configured, &App{id: "happy-app", customDomain: "shop.example.org"}, "example.com", "https://shop.example.org"},
+		{"tcp tunnel", configured, &App{id: "happy-app", tcpListener: listener, tcpPort: 20000}, "example.com", "tcp://example.com:20000"},
+		{"stock client host without domains", unconfigured, &App{id: "happy-app"}, "app.trybore.com", "https://happy-app.trybore.com"},
+		{"local host without domains", plainHTTP, &App{id: "happy-app"}, "localhost:8080", "http://happy-app.localhost:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.bs.publicURL(tt.app, tt.clientHost); got != tt.want {
+				t.Fatalf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	borepb "bore/borepb"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ipFilter decides which visitor addresses may reach a tunnel. A zero
+// ipFilter lets everyone through.
+type ipFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+func newIPFilter(allow []string, deny []string) (ipFilter, error) {
+	var filter ipFilter
+	var err error
+
+	filter.allow, err = parsePrefixes(allow)
+	if err != nil {
+		return ipFilter{}, err
+	}
+
+	filter.deny, err = parsePrefixes(deny)
+	if err != nil {
+		return ipFilter{}, err
+	}
+
+	return filter, nil
+}
+
+// parsePrefixes parses CIDR ranges, accepting bare addresses as single-host
+// ranges.
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%q is not a valid CIDR range", cidr)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+func (f ipFilter) enabled() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// check returns why addr isn't allowed through, or "" if it is.
+func (f ipFilter) check(addr netip.Addr) string {
+	if !f.enabled() {
+		return ""
+	}
+
+	if !addr.IsValid() {
+		return "unknown address"
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range f.deny {
+		if prefix.Contains(addr) {
+			return fmt.Sprintf("%s is in denied range %s", addr, prefix)
+		}
+	}
+
+	if len(f.allow) == 0 {
+		return ""
+	}
+
+	for _, prefix := range f.allow {
+		if prefix.Contains(addr) {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("%s is not in an allowed range", addr)
+}
+
+// DefaultTrustedProxies is where nginx connects from when it runs on the
+// same machine as bore-server.
+const DefaultTrustedProxies = "127.0.0.0/8,::1/128"
+
+// ParseTrustedProxies parses a comma-separated list of CIDR ranges.
+func ParseTrustedProxies(cidrs string) ([]netip.Prefix, error) {
+	var ranges []string
+	for _, cidr := range strings.Split(cidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr != "" {
+			ranges = append(ranges, cidr)
+		}
+	}
+
+	return parsePrefixes(ranges)
+}
+
+// visitorAddr is the address nginx reports for the visitor. X-Real-IP is
+// only honored from trusted proxies, otherwise anyone reaching the server
+// directly could use it to get past IP filters and rate limits.
+func (bs *BoreServer) visitorAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, _ := netip.ParseAddr(host)
+	addr = addr.Unmap()
+
+	realIP := r.Header.Get("X-Real-IP")
+	if realIP == "" || !bs.isTrustedProxy(addr) {
+		return addr
+	}
+
+	forwarded, err := netip.ParseAddr(realIP)
+	if err != nil {
+		return addr
+	}
+
+	return forwarded.Unmap()
+}
+
+func (bs *BoreServer) isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range bs.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportBlocked tells the bore client a visitor was turned away, so it shows
+// up alongside the requests that got through.
+func (bs *BoreServer) reportBlocked(app *App, blocked *borepb.Blocked, logger *zap.Logger) {
+	logger.Warn("blocked visitor", zap.String("remote_addr", blocked.RemoteAddr), zap.String("reason", blocked.Reason))
+
+	blocked.Timestamp = time.Now().UnixMilli()
+	_, err := bs.send(app, &borepb.Envelope{
+		Message: &borepb.Envelope_Blocked{Blocked: blocked},
+	})
+	if err != nil {
+		logger.Debug("failed to report blocked visitor to bore client", zap.Error(err))
+	}
+}
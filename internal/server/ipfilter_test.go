@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   string
+		want    []string
+		wantErr bool
+	}{
+		{"default", DefaultTrustedProxies, []string{"127.0.0.0/8", "::1/128"}, false},
+		{"spaces and empty entries", " 10.0.0.0/8 , ,192.168.1.5", []string{"10.0.0.0/8", "192.168.1.5/32"}, false},
+		{"unmasked range", "10.1.2.3/8", []string{"10.0.0.0/8"}, false},
+		{"nothing", "", nil, false},
+		{"invalid", "10.0.0.0/8,nginx", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefixes, err := ParseTrustedProxies(tt.cidrs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want an error, got %v", prefixes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, prefix := range prefixes {
+				got = append(got, prefix.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("want %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestVisitorAddr(t *testing.T) {
+	proxies, _ := ParseTrustedProxies("127.0.0.0/8,::1/128")
+	bs := newTestServer(t, &BoreServerCfg{TrustedProxies: proxies})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		realIP     string
+		want       string
+	}{
+		{"direct visitor", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"direct visitor spoofing", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "127.0.0.1:5000", "198.51.100.1", "198.51.100.1"},
+		{"trusted ipv6 proxy", "[::1]:5000", "2001:db8::1", "2001:db8::1"},
+		{"trusted proxy without header", "127.0.0.1:5000", "", "127.0.0.1"},
+		{"trusted proxy with garbage", "127.0.0.1:5000", "not an ip", "127.0.0.1"},
+		{"ipv4-mapped address", "[::ffff:203.0.113.7]:5000", "", "203.0.113.7"},
+		{"ipv4-mapped real ip", "127.0.0.1:5000", "::ffff:198.51.100.1", "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := bs.visitorAddr(r); got.String() != tt.want {
+				t.Fatalf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		addr    string
+		allowed bool
+	}{
+		{"no rules", nil, nil, "203.0.113.7", true},
+		{"allowed range", []string{"203.0.113.0/24"}, nil, "203.0.113.7", true},
+		{"outside allowed range", []string{"203.0.113.0/24"}, nil, "198.51.100.1", false},
+		{"bare allowed address", []string{"203.0.113.7"}, nil, "203.0.113.7", true},
+		{"denied range", nil, []string{"203.0.113.0/24"}, "203.0.113.7", false},
+		{"outside denied range", nil, []string{"203.0.113.0/24"}, "198.51.100.1", true},
+		{"deny wins over allow", []string{"203.0.113.0/24"}, []string{"203.0.113.7"}, "203.0.113.7", false},
+		{"ipv4-mapped address", []string{"203.0.113.0/24"}, nil, "::ffff:203.0.113.7", true},
+		{"ipv6 range", []string{"2001:db8::/32"}, nil, "2001:db8::1", true},
+		{"unknown address", []string{"203.0.113.0/24"}, nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newIPFilter(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			addr, _ := netip.ParseAddr(tt.addr)
+			reason := filter.check(addr)
+			if (reason == "") != tt.allowed {
+				t.Fatalf("want allowed=%v, got %q", tt.allowed, reason)
+			}
+		})
+	}
+}
+
+func TestNewIPFilterRejectsInvalidRanges(t *testing.T) {
+	for _, cidrs := range [][]string{{"10.0.0.0/33"}, {"example.com"}, {""}} {
+		if _, err := newIPFilter(cidrs, nil); err == nil {
+			t.Errorf("want an error for allow %q", cidrs)
+		}
+		if _, err := newIPFilter(nil, cidrs); err == nil {
+			t.Errorf("want an error for deny %q", cidrs)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Limits reported in logs and metrics when a request is turned away.
+const (
+	limitTunnelRate  = "tunnel_rate"
+	limitVisitorRate = "visitor_rate"
+	limitInFlight    = "in_flight"
+	limitBodySize    = "body_size"
+)
+
+// idle visitor buckets are dropped after this long
+const visitorBucketTTL = 10 * time.Minute
+
+// tokenBucket allows rate requests per second on average, with bursts of up
+// to burst requests.
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = max(1, int(math.Ceil(rate)))
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take uses up a token if there is one. Otherwise it returns how long until
+// the next token is available.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) idleSince() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.last
+}
+
+// visitorLimiter keeps a token bucket per visitor address, shared by all
+// tunnels on the server.
+type visitorLimiter struct {
+	rate      float64
+	burst     int
+	buckets   *Registry[netip.Addr, *tokenBucket]
+	mutex     sync.Mutex
+	lastSweep time.Time
+}
+
+// newVisitorLimiter returns nil when rate is 0, i.e. visitors aren't limited.
+func newVisitorLimiter(rate float64, burst int) *visitorLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &visitorLimiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   NewRegistry[netip.Addr, *tokenBucket](),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *visitorLimiter) take(addr netip.Addr, now time.Time) (bool, time.Duration) {
+	l.sweep(now)
+
+	bucket, ok := l.buckets.Lookup(addr)
+	if !ok {
+		bucket = newTokenBucket(l.rate, l.burst)
+		if !l.buckets.RegisterIfAbsent(addr, bucket) {
+			bucket, _ = l.buckets.Lookup(addr)
+		}
+	}
+
+	return bucket.take(now)
+}
+
+// sweep drops the buckets of visitors that haven't been seen in a while, so
+// the map doesn't grow with every address that ever visited.
+func (l *visitorLimiter) sweep(now time.Time) {
+	l.mutex.Lock()
+	if now.Sub(l.lastSweep) < visitorBucketTTL {
+		l.mutex.Unlock()
+		return
+	}
+	l.lastSweep = now
+	l.mutex.Unlock()
+
+	l.buckets.Range(func(addr netip.Addr, bucket *tokenBucket) bool {
+		if now.Sub(bucket.idleSince()) > visitorBucketTTL {
+			l.buckets.Unregister(addr)
+		}
+		return true
+	})
+}
+
+func newLimitHits() map[string]*atomic.Int64 {
+	return map[string]*atomic.Int64{
+		limitTunnelRate:  {},
+		limitVisitorRate: {},
+		limitInFlight:    {},
+		limitBodySize:    {},
+	}
+}
+
+// limitExceeded counts a request turned away by limit.
+func (bs *BoreServer) limitExceeded(limit string, reqLogger *zap.Logger) {
+	bs.limitHits[limit].Add(1)
+	reqLogger.Warn("request limit exceeded", zap.String("limit", limit))
+}
+
+// renderBodyTooLarge tells the visitor their request body was over the
+// tunnel's limit.
+func (bs *BoreServer) renderBodyTooLarge(w http.ResponseWriter) {
+	renderErrorPage(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request bodies are limited to %d bytes on this tunnel.", bs.maxBodySize))
+}
+
+// admit applies the rate and concurrency limits to a new request or
+// connection. A nil release means it must be turned away because of limit,
+// otherwise release must be called once it is done.
+func (bs *BoreServer) admit(app *App, remoteAddr netip.Addr, reqLogger *zap.Logger) (release func(), limit string, retryAfter time.Duration) {
+	now := time.Now()
+
+	if bs.visitorLimiter != nil {
+		ok, wait := bs.visitorLimiter.take(remoteAddr, now)
+		if !ok {
+			bs.limitExceeded(limitVisitorRate, reqLogger)
+			return nil, limitVisitorRate, wait
+		}
+	}
+
+	if app.rateLimit != nil {
+		ok, wait := app.rateLimit.take(now)
+		if !ok {
+			bs.limitExceeded(limitTunnelRate, reqLogger)
+			return nil, limitTunnelRate, wait
+		}
+	}
+
+	if bs.maxInFlight > 0 {
+		if app.inFlight.Add(1) > int64(bs.maxInFlight) {
+			app.inFlight.Add(-1)
+			bs.limitExceeded(limitInFlight, reqLogger)
+			return nil, limitInFlight, time.Second
+		}
+
+		return func() { app.inFlight.Add(-1) }, "", 0
+	}
+
+	return func() {}, "", 0
+}
+
+func renderTooManyRequests(w http.ResponseWriter, limit string, retryAfter time.Duration) {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	message := "This tunnel is receiving too many requests. Please retry shortly."
+	switch limit {
+	case limitVisitorRate:
+		message = fmt.Sprintf("You are sending too many requests. Please retry in %d seconds.", seconds)
+	case limitInFlight:
+		message = "This tunnel is busy with too many requests. Please retry shortly."
+	}
+
+	renderErrorPage(w, http.StatusTooManyRequests, message)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+type metrics struct {
+	registry      *prometheus.Registry
+	reconnects    prometheus.Counter
+	requests      *prometheus.CounterVec
+	requestBytes  prometheus.Counter
+	responseBytes prometheus.Counter
+	roundTrip     prometheus.Histogram
+}
+
+func newMetrics(bs *BoreServer) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "bore_websocket_reconnects_total",
+			Help: "Bore clients that reconnected and resumed their tunnel.",
+		}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bore_requests_total",
+			Help: "HTTP requests to tunnels, by response status class.",
+		}, []string{"status_class"}),
+		requestBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "bore_request_bytes_total",
+			Help: "Request body bytes read from visitors.",
+		}),
+		responseBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "bore_response_bytes_total",
+			Help: "Response body bytes written to visitors.",
+		}),
+		roundTrip: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "bore_tunnel_round_trip_seconds",
+			Help:    "Time from forwarding a request to the bore client until its response headers arrive.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.reconnects,
+		m.requests,
+		m.requestBytes,
+		m.responseBytes,
+		m.roundTrip,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "bore_active_tunnels",
+			Help: "Tunnels registered on the server, including ones waiting for their client to reconnect.",
+		}, func() float64 {
+			return float64(bs.apps.Len())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "bore_pending_requests",
+			Help: "Requests waiting on or streaming a response from a bore client.",
+		}, func() float64 {
+			return float64(bs.reqIdChanMap.Len())
+		}),
+	)
+
+	for limit, hits := range bs.limitHits {
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "bore_limit_hits_total",
+			Help:        "Requests turned away by a rate, concurrency or size limit.",
+			ConstLabels: prometheus.Labels{"limit": limit},
+		}, func() float64 {
+			return float64(hits.Load())
+		}))
+	}
+
+	return m
+}
+
+// instrument records the status and body sizes of every tunnel request.
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r.Body = &countingReader{ReadCloser: r.Body, counter: m.requestBytes}
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+				if websocket.IsWebSocketUpgrade(r) {
+					// the upgrade response is written to the hijacked connection
+					status = http.StatusSwitchingProtocols
+				}
+			}
+
+			m.requests.WithLabelValues(fmt.Sprintf("%dxx", status/100)).Inc()
+			m.responseBytes.Add(float64(ww.BytesWritten()))
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+func (m *metrics) observeRoundTrip(sentAt time.Time) {
+	m.roundTrip.Observe(time.Since(sentAt).Seconds())
+}
+
+type countingReader struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.counter.Add(float64(n))
+
+	return n, err
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed templates/error.html
+var errorPageHTML string
+
+var errorPage = template.Must(template.New("error").Parse(errorPageHTML))
+
+func renderErrorPage(w http.ResponseWriter, statusCode int, message string) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	return errorPage.Execute(w, map[string]any{
+		"StatusCode": statusCode,
+		"StatusText": http.StatusText(statusCode),
+		"Message":    message,
+	})
+}
@@ -0,0 +1,94 @@
+package server
+
+import "sync"
+
+// Registry is a map that is safe for concurrent use by the ws handler, app
+// goroutines and request goroutines.
+type Registry[K comparable, V any] struct {
+	mutex sync.RWMutex
+	items map[K]V
+}
+
+func NewRegistry[K comparable, V any]() *Registry[K, V] {
+	return &Registry[K, V]{
+		items: make(map[K]V),
+	}
+}
+
+func (r *Registry[K, V]) Lookup(key K) (V, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	value, ok := r.items[key]
+	return value, ok
+}
+
+func (r *Registry[K, V]) Register(key K, value V) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.items[key] = value
+}
+
+func (r *Registry[K, V]) RegisterIfAbsent(key K, value V) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.items[key]; ok {
+		return false
+	}
+
+	r.items[key] = value
+	return true
+}
+
+func (r *Registry[K, V]) Unregister(key K) (V, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	value, ok := r.items[key]
+	delete(r.items, key)
+
+	return value, ok
+}
+
+// UnregisterIf removes key only if its current value satisfies match, so a
+// stale owner can't remove an entry that has since been replaced.
+func (r *Registry[K, V]) UnregisterIf(key K, match func(V) bool) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	value, ok := r.items[key]
+	if !ok || !match(value) {
+		return false
+	}
+
+	delete(r.items, key)
+	return true
+}
+
+// Range calls fn for a snapshot of the registry, so fn is free to call back
+// into the registry. Iteration stops when fn returns false.
+func (r *Registry[K, V]) Range(fn func(key K, value V) bool) {
+	r.mutex.RLock()
+	keys := make([]K, 0, len(r.items))
+	values := make([]V, 0, len(r.items))
+	for key, value := range r.items {
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	r.mutex.RUnlock()
+
+	for i := range keys {
+		if !fn(keys[i], values[i]) {
+			return
+		}
+	}
+}
+
+func (r *Registry[K, V]) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return len(r.items)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegistryLookupAndUnregister(t *testing.T) {
+	r := NewRegistry[string, int]()
+
+	if _, ok := r.Lookup("a"); ok {
+		t.Fatal("Lookup found a key in an empty registry")
+	}
+
+	r.Register("a", 1)
+	if value, ok := r.Lookup("a"); !ok || value != 1 {
+		t.Fatalf("want 1, got %d (found %v)", value, ok)
+	}
+
+	r.Register("a", 2)
+	if value, _ := r.Lookup("a"); value != 2 {
+		t.Fatalf("want Register to replace the value, got %d", value)
+	}
+
+	if value, ok := r.Unregister("a"); !ok || value != 2 {
+		t.Fatalf("want Unregister to return 2, got %d (found %v)", value, ok)
+	}
+	if _, ok := r.Unregister("a"); ok {
+		t.Fatal("Unregister found a key that was already removed")
+	}
+	if r.Len() != 0 {
+		t.Fatalf("want an empty registry, got %d", r.Len())
+	}
+}
+
+func TestRegistryRegisterIfAbsent(t *testing.T) {
+	r := NewRegistry[string, int]()
+
+	if !r.RegisterIfAbsent("a", 1) {
+		t.Fatal("RegisterIfAbsent refused a new key")
+	}
+	if r.RegisterIfAbsent("a", 2) {
+		t.Fatal("RegisterIfAbsent replaced an existing key")
+	}
+	if value, _ := r.Lookup("a"); value != 1 {
+		t.Fatalf("want the first value kept, got %d", value)
+	}
+}
+
+func TestRegistryUnregisterIf(t *testing.T) {
+	r := NewRegistry[string, int]()
+	r.Register("a", 1)
+
+	if r.UnregisterIf("a", func(value int) bool { return value == 2 }) {
+		t.Fatal("UnregisterIf removed a key that didn't match")
+	}
+	if !r.UnregisterIf("a", func(value int) bool { return value == 1 }) {
+		t.Fatal("UnregisterIf kept a key that matched")
+	}
+	if r.UnregisterIf("missing", func(int) bool { return true }) {
+		t.Fatal("UnregisterIf removed a missing key")
+	}
+}
+
+func TestRegistryRangeAllowsCallingBack(t *testing.T) {
+	r := NewRegistry[int, int]()
+	for i := range 10 {
+		r.Register(i, i)
+	}
+
+	seen := 0
+	r.Range(func(key int, value int) bool {
+		seen++
+		r.Unregister(key)
+		return true
+	})
+	if seen != 10 || r.Len() != 0 {
+		t.Fatalf("want 10 keys seen and removed, saw %d with %d left", seen, r.Len())
+	}
+
+	for i := range 10 {
+		r.Register(i, i)
+	}
+	seen = 0
+	r.Range(func(int, int) bool {
+		seen++
+		return seen < 3
+	})
+	if seen != 3 {
+		t.Fatalf("want Range to stop after 3 keys, saw %d", seen)
+	}
+}
+
+func TestRegistryRegisterIfAbsentHasOneWinner(t *testing.T) {
+	r := NewRegistry[string, int]()
+
+	var wins atomic.Int64
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r.RegisterIfAbsent("app", i) {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if wins.Load() != 1 {
+		t.Fatalf("want exactly one RegisterIfAbsent to win, got %d", wins.Load())
+	}
+}
+
+func TestRegistryConcurrentUse(t *testing.T) {
+	r := NewRegistry[string, int]()
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range 500 {
+				key := fmt.Sprintf("%d-%d", worker, i%20)
+				r.RegisterIfAbsent(key, i)
+				r.Lookup(key)
+				r.Range(func(string, int) bool { return true })
+				if i%3 == 0 {
+					r.Unregister(key)
+				}
+				r.UnregisterIf(key, func(value int) bool { return value%2 == 0 })
+				r.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	r.Range(func(key string, value int) bool {
+		if got, ok := r.Lookup(key); !ok || got != value {
+			t.Errorf("Range returned %s=%d, Lookup %d (found %v)", key, value, got, ok)
+		}
+		return true
+	})
+}
@@ -3,13 +3,19 @@ package server
 import (
 	borepb "bore/borepb"
 	"bore/internal/logger"
+	"bore/internal/mux"
+	"bore/internal/protocol"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/netip"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	haikunator "github.com/atrox/haikunatorgo/v2"
@@ -22,52 +28,423 @@ import (
 
 const maxRetries int = 10
 
+const (
+	bodyChunkSize      = 32 * 1024
+	responseBufferSize = 16
+	statsInterval      = 30 * time.Second
+)
+
+var errAppDetached = errors.New("app is not connected")
+
 type App struct {
-	wsConn  *websocket.Conn
-	wsMutex *sync.Mutex
+	id            string
+	wsConn        *websocket.Conn
+	wsMutex       *sync.Mutex
+	disconnected  chan struct{}
+	resumeToken   string
+	expiry        *time.Timer
+	expired       bool
+	streams       *mux.Session
+	features      []string
+	stats         *borepb.Stats
+	tcpListener   net.Listener
+	tcpPort       int
+	token         *token
+	basicAuth     string
+	bearerToken   string
+	ipFilter      ipFilter
+	rateLimit     *tokenBucket
+	inFlight      atomic.Int64
+	clientIP      string
+	clientVersion string
+	connectedAt   time.Time
+	requests      atomic.Int64
+	customDomain  string
+	publicURL     string
+}
+
+type pendingRequest struct {
+	responses    chan *borepb.Response
+	done         chan struct{}
+	bodyTooLarge chan struct{}
+	uploaded     chan struct{}
 }
 
 type BoreServer struct {
-	logger       *zap.Logger
-	reqIdChanMap map[string]chan *borepb.Response
-	apps         map[string]App
-	haikunator   *haikunator.Haikunator
-	port         int
+	logger             *zap.Logger
+	reqIdChanMap       *Registry[string, *pendingRequest]
+	apps               *Registry[string, *App]
+	resumeTokens       *Registry[string, string]
+	haikunator         *haikunator.Haikunator
+	haikunatorMutex    sync.Mutex
+	port               int
+	resumeGracePeriod  time.Duration
+	requestTimeout     time.Duration
+	tcpPortMin         int
+	tcpPortMax         int
+	version            string
+	reservedSubdomains *Registry[string, struct{}]
+	tokens             *tokenStore
+	rateLimit          float64
+	rateBurst          int
+	visitorLimiter     *visitorLimiter
+	maxBodySize        int64
+	maxInFlight        int
+	limitHits          map[string]*atomic.Int64
+	metrics            *metrics
+	adminAddr          string
+	adminToken         string
+	drainTimeout       time.Duration
+	draining           atomic.Bool
+	tlsCert            string
+	tlsKey             string
+	acme               bool
+	acmeEmail          string
+	acmeDirectory      string
+	acmeCacheDir       string
+	httpsPort          int
+	httpPort           int
+	customDomains      *Registry[string, string]
+	baseDomains        []baseDomain
+	host               string
+	scheme             string
+	trustedProxies     []netip.Prefix
 }
 
 type BoreServerCfg struct {
-	Port    int
-	LogFile string
-	Version string
+	Port               int
+	LogFile            string
+	Version            string
+	ResumeGracePeriod  time.Duration
+	RequestTimeout     time.Duration
+	TCPPortMin         int
+	TCPPortMax         int
+	ReservedSubdomains []string
+	Tokens             []TokenConfig
+	RateLimit          float64
+	RateBurst          int
+	VisitorRateLimit   float64
+	VisitorRateBurst   int
+	MaxBodySize        int64
+	MaxInFlight        int
+	AdminAddr          string
+	AdminToken         string
+	DrainTimeout       time.Duration
+	TLSCert            string
+	TLSKey             string
+	ACME               bool
+	ACMEEmail          string
+	ACMEDirectory      string
+	ACMECacheDir       string
+	HTTPSPort          int
+	HTTPPort           int
+	Domains            []string
+	Host               string
+	Scheme             string
+	TrustedProxies     []netip.Prefix
+}
+
+func (app *App) conn() *websocket.Conn {
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	return app.wsConn
+}
+
+func (bs *BoreServer) sendFrame(app *App, frame mux.Frame) error {
+	_, err := bs.send(app, &borepb.Envelope{
+		Message: &borepb.Envelope_Frame{Frame: frame.Proto()},
+	})
+
+	return err
+}
+
+// writeMessage returns a channel that is closed when the connection the
+// message was written to goes away.
+func (app *App) writeMessage(data []byte) (<-chan struct{}, error) {
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	if app.wsConn == nil {
+		return nil, errAppDetached
+	}
+
+	return app.disconnected, app.wsConn.WriteMessage(websocket.BinaryMessage, data)
 }
 
 func (bs *BoreServer) generateAppId() string {
+	bs.haikunatorMutex.Lock()
+	defer bs.haikunatorMutex.Unlock()
+
 	return bs.haikunator.Haikunate()
 }
 
-func (bs *BoreServer) handleApp(appId string) {
-	defer func() {
-		delete(bs.apps, appId)
-		bs.logger.Info("cleaned up resources for app", zap.String("app_id", appId))
-	}()
+// registerApp registers a new app under the requested subdomain, or under a
+// random ID when none was requested. Visitors and the admin API read the app
+// without locking, so it is fully set up before it is published in bs.apps.
+func (bs *BoreServer) registerApp(hello *borepb.Hello, tok *token, clientHost string) (*App, error) {
+	app := &App{
+		wsMutex:      &sync.Mutex{},
+		resumeToken:  uuid.New().String(),
+		token:        tok,
+		basicAuth:    hello.BasicAuth,
+		bearerToken:  hello.BearerToken,
+		customDomain: normalizeDomain(hello.CustomDomain),
+	}
+	subdomain := hello.Subdomain
+	customDomain := app.customDomain
+
+	var err error
+	app.ipFilter, err = newIPFilter(hello.AllowCidrs, hello.DenyCidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if customDomain != "" {
+		err := bs.verifyDomain(customDomain, hello.DomainKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if bs.rateLimit > 0 {
+		app.rateLimit = newTokenBucket(bs.rateLimit, bs.rateBurst)
+	}
+	app.streams = mux.NewSession(func(frame mux.Frame) error {
+		return bs.sendFrame(app, frame)
+	})
+
+	if protocol.Supports(hello.Features, protocol.FeatureTCP) {
+		err := bs.listenTCP(app)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if subdomain != "" {
+		bs.takeOverApp(subdomain, tok)
+	}
+
+	err = bs.tokens.acquire(tok)
+	if err != nil {
+		app.closeTCPListener()
+		return nil, err
+	}
+
+	if subdomain != "" {
+		err := bs.validateSubdomain(subdomain, tok)
+		if err != nil {
+			bs.tokens.release(tok)
+			app.closeTCPListener()
+			return nil, err
+		}
+
+		app.id = subdomain
+		app.publicURL = bs.publicURL(app, clientHost)
+		if !bs.apps.RegisterIfAbsent(app.id, app) {
+			bs.tokens.release(tok)
+			app.closeTCPListener()
+			return nil, fmt.Errorf("subdomain %q is already in use", subdomain)
+		}
+	} else {
+		for {
+			app.id = bs.generateAppId()
+			app.publicURL = bs.publicURL(app, clientHost)
+			if bs.apps.RegisterIfAbsent(app.id, app) {
+				break
+			}
+		}
+	}
+
+	bs.resumeTokens.Register(app.resumeToken, app.id)
+
+	if customDomain != "" && !bs.customDomains.RegisterIfAbsent(customDomain, app.id) {
+		bs.unregisterApp(app)
+		return nil, fmt.Errorf("custom domain %q is already in use", customDomain)
+	}
+
+	return app, nil
+}
+
+func (bs *BoreServer) unregisterApp(app *App) {
+	unregistered := bs.apps.UnregisterIf(app.id, func(registered *App) bool {
+		return registered == app
+	})
+	if unregistered {
+		bs.tokens.release(app.token)
+	}
+	bs.resumeTokens.Unregister(app.resumeToken)
+	if app.customDomain != "" {
+		bs.customDomains.UnregisterIf(app.customDomain, func(appId string) bool {
+			return appId == app.id
+		})
+	}
+
+	app.closeTCPListener()
+}
+
+func (bs *BoreServer) resumeApp(resumeToken string, tok *token) (*App, bool) {
+	if resumeToken == "" {
+		return nil, false
+	}
 
-	app, ok := bs.apps[appId]
+	appId, ok := bs.resumeTokens.Lookup(resumeToken)
 	if !ok {
-		bs.logger.Error("No App found!")
+		return nil, false
+	}
+
+	app, ok := bs.apps.Lookup(appId)
+	if !ok || app.token != tok {
+		return nil, false
+	}
+
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	if app.expired {
+		return nil, false
+	}
+
+	if app.expiry != nil {
+		app.expiry.Stop()
+		app.expiry = nil
+	}
+
+	return app, true
+}
+
+func (bs *BoreServer) detachApp(app *App, conn *websocket.Conn) {
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	if app.wsConn != conn || app.expired {
 		return
 	}
 
-	if app.wsConn == nil {
-		bs.logger.Info("no wsConn for app", zap.String("app_id", appId))
+	if conn != nil {
+		close(app.disconnected)
+		app.streams.Reset(errAppDetached)
+	}
+
+	app.wsConn = nil
+
+	var expiry *time.Timer
+	expiry = time.AfterFunc(bs.resumeGracePeriod, func() {
+		app.wsMutex.Lock()
+		if app.expiry != expiry || app.wsConn != nil {
+			app.wsMutex.Unlock()
+			return
+		}
+		app.expired = true
+		app.wsMutex.Unlock()
+
+		bs.unregisterApp(app)
+		bs.logger.Info("cleaned up resources for app", zap.String("app_id", app.id))
+	})
+	app.expiry = expiry
+
+	bs.logger.Info("app detached, waiting for client to resume", zap.String("app_id", app.id), zap.Duration("grace_period", bs.resumeGracePeriod))
+}
+
+// takeOverApp releases the detached app on subdomain if it was opened with
+// tok, so a client that exited without saying goodbye can claim its
+// subdomain again right away instead of waiting out the resume grace period.
+// Without tokens there is no telling the client apart from anyone else, so
+// the app is left to be resumed.
+func (bs *BoreServer) takeOverApp(subdomain string, tok *token) {
+	if tok == nil {
 		return
 	}
 
-	go bs.ping(&app)
+	app, ok := bs.apps.Lookup(subdomain)
+	if !ok || app.token != tok {
+		return
+	}
+
+	app.wsMutex.Lock()
+	if app.wsConn != nil || app.expired {
+		app.wsMutex.Unlock()
+		return
+	}
+	if app.expiry != nil {
+		app.expiry.Stop()
+		app.expiry = nil
+	}
+	app.expired = true
+	app.wsMutex.Unlock()
+
+	bs.unregisterApp(app)
+	bs.logger.Info("released detached app for a new client with its token", zap.String("app_id", app.id), zap.String("token", tok.name))
+}
+
+// releaseApp unregisters an app whose client has said it won't be back,
+// without waiting out the resume grace period.
+func (bs *BoreServer) releaseApp(app *App, conn *websocket.Conn) {
+	app.wsMutex.Lock()
+	if conn != nil && app.wsConn == conn {
+		close(app.disconnected)
+		app.streams.Reset(errAppDetached)
+		app.wsConn = nil
+	}
+	if app.expiry != nil {
+		app.expiry.Stop()
+		app.expiry = nil
+	}
+	app.expired = true
+	app.wsMutex.Unlock()
+
+	bs.unregisterApp(app)
+	bs.logger.Info("cleaned up resources for app", zap.String("app_id", app.id))
+}
+
+func (bs *BoreServer) attachApp(app *App, conn *websocket.Conn, hello *borepb.Hello, clientIP string) {
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	if app.wsConn != nil {
+		bs.logger.Info("closing stale connection for resumed app", zap.String("app_id", app.id))
+		app.wsConn.Close()
+		close(app.disconnected)
+		app.streams.Reset(errAppDetached)
+	}
+
+	app.wsConn = conn
+	app.disconnected = make(chan struct{})
+	app.features = hello.Features
+	app.clientIP = clientIP
+	app.clientVersion = hello.ClientVersion
+	app.connectedAt = time.Now()
+}
+
+func (app *App) setStats(stats *borepb.Stats) {
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	app.stats = stats
+}
+
+func (app *App) supports(feature string) bool {
+	app.wsMutex.Lock()
+	defer app.wsMutex.Unlock()
+
+	return protocol.Supports(app.features, feature)
+}
+
+func (bs *BoreServer) handleApp(app *App, conn *websocket.Conn) {
+	defer bs.detachApp(app, conn)
+
+	go bs.ping(app, conn)
+
+	bs.send(app, &borepb.Envelope{
+		Message: &borepb.Envelope_ConfigUpdate{ConfigUpdate: &borepb.ConfigUpdate{
+			StatsIntervalMs: statsInterval.Milliseconds(),
+		}},
+	})
 
 	for {
-		response := &borepb.Response{}
+		envelope := &borepb.Envelope{}
 
-		_, res, err := app.wsConn.ReadMessage()
+		_, res, err := conn.ReadMessage()
 
 		if websocket.IsUnexpectedCloseError(err) {
 			bs.logger.Info("ws conn closed unexpectedly", zap.Error(err))
@@ -79,17 +456,65 @@ func (bs *BoreServer) handleApp(appId string) {
 			return
 		}
 
-		err = proto.Unmarshal(res, response)
+		err = proto.Unmarshal(res, envelope)
 		if err != nil {
 			bs.logger.Error("Failed to unmarshal response", zap.Error(err))
 			return
 		}
 
-		bs.reqIdChanMap[response.Id] <- response
+		switch message := envelope.Message.(type) {
+		case *borepb.Envelope_Response:
+			bs.handleResponse(app, message.Response)
+
+		case *borepb.Envelope_Frame:
+			ok := app.streams.Dispatch(mux.FrameFromProto(message.Frame))
+			if !ok {
+				bs.logger.Debug("dropping frame for closed stream", zap.String("req_id", message.Frame.StreamId), zap.String("app_id", app.id))
+			}
+
+		case *borepb.Envelope_Error:
+			bs.logger.Warn("bore client reported an error", zap.String("app_id", app.id), zap.String("req_id", message.Error.Id), zap.String("error", message.Error.Message))
+
+		case *borepb.Envelope_Stats:
+			app.setStats(message.Stats)
+			bs.logger.Debug("received stats from bore client", zap.String("app_id", app.id), zap.Int64("open_streams", message.Stats.OpenStreams), zap.Int64("requests_total", message.Stats.RequestsTotal))
+
+		case *borepb.Envelope_Shutdown:
+			bs.logger.Info("bore client is shutting down", zap.String("app_id", app.id), zap.String("reason", message.Shutdown.Reason))
+			bs.releaseApp(app, conn)
+			return
+
+		default:
+			messageType := fmt.Sprintf("%T", envelope.Message)
+			bs.logger.Warn("dropping unexpected message from bore client", zap.String("app_id", app.id), zap.String("type", messageType))
+
+			// the client only logs errors, so this can't bounce back and forth
+			_, err := bs.send(app, &borepb.Envelope{
+				Message: &borepb.Envelope_Error{Error: &borepb.Error{
+					Message: fmt.Sprintf("bore server can't handle message of type %s", messageType),
+				}},
+			})
+			if err != nil {
+				bs.logger.Debug("failed to report unexpected message to bore client", zap.String("app_id", app.id), zap.Error(err))
+			}
+		}
 	}
 }
 
-func (bs *BoreServer) ping(app *App) {
+func (bs *BoreServer) handleResponse(app *App, response *borepb.Response) {
+	pending, ok := bs.reqIdChanMap.Lookup(response.Id)
+	if !ok {
+		bs.logger.Warn("dropping response for unknown request", zap.String("req_id", response.Id), zap.String("app_id", app.id))
+		return
+	}
+
+	select {
+	case pending.responses <- response:
+	case <-pending.done:
+	}
+}
+
+func (bs *BoreServer) ping(app *App, conn *websocket.Conn) {
 	pingInterval := time.Duration(10 * time.Second)
 	ticker := time.NewTicker(pingInterval)
 
@@ -97,7 +522,7 @@ func (bs *BoreServer) ping(app *App) {
 
 	for range ticker.C {
 		app.wsMutex.Lock()
-		err := app.wsConn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
+		err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
 		app.wsMutex.Unlock()
 
 		if err != nil {
@@ -107,23 +532,207 @@ func (bs *BoreServer) ping(app *App) {
 	}
 }
 
-func (bs *BoreServer) StartBoreServer() error {
+func (bs *BoreServer) sendRequest(app *App, req *borepb.Request) (<-chan struct{}, error) {
+	return bs.send(app, &borepb.Envelope{
+		Message: &borepb.Envelope_Request{Request: req},
+	})
+}
+
+// cancelRequest tells the bore client to stop working on a request nobody is
+// waiting for anymore.
+func (bs *BoreServer) cancelRequest(app *App, requestId string, reason string, reqLogger *zap.Logger) {
+	_, err := bs.send(app, &borepb.Envelope{
+		Message: &borepb.Envelope_Cancel{Cancel: &borepb.Cancel{Id: requestId, Reason: reason}},
+	})
+	if err != nil {
+		reqLogger.Debug("failed to send cancel to bore client", zap.Error(err))
+		return
+	}
+
+	reqLogger.Info("cancelled request on bore client", zap.String("reason", reason))
+}
+
+func (bs *BoreServer) send(app *App, envelope *borepb.Envelope) (<-chan struct{}, error) {
+	message, err := proto.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.writeMessage(message)
+}
+
+func (bs *BoreServer) streamRequestBody(stream *mux.Stream, body io.Reader, reqLogger *zap.Logger) error {
+	size, err := io.Copy(stream, body)
+	if err != nil {
+		reqLogger.Error("failed to stream request body", zap.Error(err), zap.Int64("req_size", size))
+		// so the upstream sees a failed upload rather than a short one
+		stream.CloseWithError(fmt.Sprintf("failed to read the visitor's request body: %s", err))
+		return err
+	}
+
+	err = stream.CloseWrite()
+	if err != nil {
+		reqLogger.Error("failed to end request body stream", zap.Error(err))
+		return err
+	}
+
+	reqLogger.Debug("request body streamed", zap.Int64("req_size", size))
+	return nil
+}
+
+// renderClientError tells the visitor why the bore client answered with an
+// error instead of the upstream's response.
+func renderClientError(w http.ResponseWriter, response *borepb.Response, reqLogger *zap.Logger) {
+	if response.Error == protocol.ErrorShuttingDown {
+		reqLogger.Info("bore client is shutting down, turned the request away")
+		renderErrorPage(w, http.StatusServiceUnavailable, "This tunnel is shutting down.")
+		return
+	}
+
+	reqLogger.Warn("bore client could not reach upstream", zap.String("error", response.Error))
+	renderErrorPage(w, http.StatusBadGateway, fmt.Sprintf("The bore client is running, but the %s.", response.Error))
+}
+
+// forwardResponse writes the response to the visitor, streaming the body as
+// it arrives. The request timeout only covers the wait for headers once the
+// request body is uploaded, since slow uploads and streamed responses like
+// SSE can legitimately take much longer.
+func (bs *BoreServer) forwardResponse(w http.ResponseWriter, r *http.Request, app *App, pending *pendingRequest, stream *mux.Stream, disconnected <-chan struct{}, reqLogger *zap.Logger) {
+	sentAt := time.Now()
+	uploaded := pending.uploaded
+	var timeout <-chan time.Time
+	var response *borepb.Response
+
+	for response == nil {
+		select {
+		case <-uploaded:
+			uploaded = nil
+			timer := time.NewTimer(bs.requestTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		case response = <-pending.responses:
+		case <-disconnected:
+			reqLogger.Warn("bore client disconnected with request in flight")
+			renderErrorPage(w, http.StatusBadGateway, "The bore client disconnected before it could respond.")
+			return
+		case <-pending.bodyTooLarge:
+			bs.renderBodyTooLarge(w)
+			return
+		case <-timeout:
+			reqLogger.Warn("timed out waiting for response", zap.Duration("timeout", bs.requestTimeout))
+			bs.cancelRequest(app, stream.ID(), fmt.Sprintf("no response within %s", bs.requestTimeout), reqLogger)
+			renderErrorPage(w, http.StatusGatewayTimeout, fmt.Sprintf("The bore client did not respond within %s.", bs.requestTimeout))
+			return
+		case <-r.Context().Done():
+			reqLogger.Info("visitor went away before response completed", zap.Error(r.Context().Err()))
+			bs.cancelRequest(app, stream.ID(), "visitor disconnected", reqLogger)
+			return
+		}
+	}
+
+	bs.metrics.observeRoundTrip(sentAt)
+
+	if response.Error != "" {
+		renderClientError(w, response, reqLogger)
+		return
+	}
+
+	reqLogger.Info("received response", zap.Int32("status_code", response.StatusCode), zap.Any("headers", response.Headers))
+
+	for headerName, headerValues := range response.Headers {
+		w.Header().Add(headerName, headerValues)
+	}
+
+	if response.Frame != borepb.FrameType_FRAME_START {
+		w.WriteHeader(int(response.StatusCode))
+
+		n, err := w.Write(response.Body)
+		if err != nil {
+			reqLogger.Error("failed to forward response to bore client", zap.Error(err))
+			return
+		}
+
+		reqLogger.Info("response forwarded to bore client", zap.Int("res_size", n))
+		return
+	}
+
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(int(response.StatusCode))
+
+	// a visitor going away has to unblock the read below
+	stop := context.AfterFunc(r.Context(), func() {
+		stream.Close()
+	})
+	defer stop()
+
+	flusher, canFlush := w.(http.Flusher)
+	buf := make([]byte, bodyChunkSize)
+	size := 0
+
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			written, writeErr := w.Write(buf[:n])
+			size += written
+			if writeErr != nil {
+				reqLogger.Error("failed to forward response to bore client", zap.Error(writeErr))
+				return
+			}
+
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+
+		if err == io.EOF {
+			reqLogger.Info("response forwarded to bore client", zap.Int("res_size", size))
+			return
+		}
+
+		if err != nil {
+			switch {
+			case r.Context().Err() != nil:
+				reqLogger.Info("visitor went away before response completed", zap.Error(r.Context().Err()))
+				bs.cancelRequest(app, stream.ID(), "visitor disconnected", reqLogger)
+				return
+			case errors.Is(err, errAppDetached):
+				reqLogger.Warn("bore client disconnected with request in flight", zap.Int("res_size", size))
+			default:
+				reqLogger.Warn("upstream response aborted", zap.Error(err), zap.Int("res_size", size))
+			}
+
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// routes serves bore clients on /ws and visitors on everything else.
+func (bs *BoreServer) routes() http.Handler {
 	router := chi.NewRouter()
 
-	router.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
-		clientIP := r.Header.Get("X-Real-IP")
+	handleClient := func(w http.ResponseWriter, r *http.Request) {
+		clientIP := bs.visitorAddr(r).String()
 		bs.logger.Info("new bore client connection request", zap.String("client_ip", clientIP))
 
+		// a plain error rather than a rejection, so the client retries
+		if bs.draining.Load() {
+			http.Error(w, "bore server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+
+		err := checkProtocolHeader(r)
+		if err != nil {
+			bs.logger.Warn("rejected incompatible bore client", zap.Error(err), zap.String("client_ip", clientIP), zap.String("protocol_version", r.Header.Get(protocol.Header)))
+			http.Error(w, err.Error(), http.StatusUpgradeRequired)
+			return
+		}
+
 		var upgrader = websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 		}
 
-		appId := bs.generateAppId()
-
-		conn, err := upgrader.Upgrade(w, r, http.Header{
-			"X-Bore-App-ID": {appId},
-		})
+		conn, err := upgrader.Upgrade(w, r, nil)
 
 		if err != nil {
 			bs.logger.Error("failed to upgrade connection to WS", zap.Error(err), zap.String("client_ip", clientIP))
@@ -133,22 +742,82 @@ func (bs *BoreServer) StartBoreServer() error {
 
 		bs.logger.Info("connection upgraded to WS", zap.String("client_ip", clientIP))
 
-		bs.apps[appId] = App{
-			wsConn:  conn,
-			wsMutex: &sync.Mutex{},
+		hello, err := bs.readHello(conn)
+		if err != nil {
+			bs.logger.Error("failed to read hello from bore client", zap.Error(err), zap.String("client_ip", clientIP))
+			conn.Close()
+			return
 		}
-		bs.logger.Info("registered app!", zap.String("app_id", appId))
 
-		go bs.handleApp(appId)
-	})
+		version, err := protocol.Negotiate(hello.ProtocolVersion)
+		if err != nil {
+			bs.logger.Warn("rejected incompatible bore client", zap.Error(err), zap.String("client_ip", clientIP), zap.String("client_version", hello.ClientVersion))
+			bs.rejectClient(conn, fmt.Sprintf("This bore client is out of date: %s. Please upgrade bore.", err))
+			return
+		}
 
-	router.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
+		tok, err := bs.tokens.authenticate(hello.Token)
+		if err != nil {
+			bs.logger.Warn("rejected unauthenticated bore client", zap.Error(err), zap.String("client_ip", clientIP))
+			bs.rejectClient(conn, err.Error())
+			return
+		}
+
+		app, resumed := bs.resumeApp(hello.ResumeToken, tok)
+		if !resumed {
+			app, err = bs.registerApp(hello, tok, r.Host)
+			if err != nil {
+				bs.logger.Warn("failed to register app", zap.Error(err), zap.String("client_ip", clientIP), zap.String("subdomain", hello.Subdomain), zap.String("custom_domain", hello.CustomDomain))
+				bs.rejectClient(conn, err.Error())
+				return
+			}
+
+			if app.tcpListener != nil {
+				go bs.serveTCP(app)
+			}
+		}
+
+		err = bs.writeWelcome(conn, &borepb.Welcome{
+			ProtocolVersion: version,
+			Capabilities:    bs.capabilities(),
+			AppId:           app.id,
+			ResumeToken:     app.resumeToken,
+			TcpPort:         int32(app.tcpPort),
+			CustomDomain:    app.customDomain,
+			PublicUrl:       app.publicURL,
+		})
+		if err != nil {
+			bs.logger.Error("failed to write welcome to bore client", zap.Error(err), zap.String("client_ip", clientIP))
+			conn.Close()
+
+			if resumed {
+				bs.detachApp(app, nil)
+			} else {
+				bs.unregisterApp(app)
+			}
+			return
+		}
+
+		bs.attachApp(app, conn, hello, clientIP)
+
+		if resumed {
+			bs.metrics.reconnects.Inc()
+			bs.logger.Info("resumed app!", zap.String("app_id", app.id), zap.String("client_version", hello.ClientVersion))
+		} else {
+			bs.logger.Info("registered app!", zap.String("app_id", app.id), zap.String("client_version", hello.ClientVersion))
+		}
+
+		go bs.handleApp(app, conn)
+	}
+
+	handleVisitor := func(w http.ResponseWriter, r *http.Request) {
 		requestId := uuid.New().String()
-		appId := strings.Split(r.Host, ".")[0]
-		clientIP := r.Header.Get("X-Real-IP")
+		appId, _ := bs.appIdForHost(r.Host)
+		remoteAddr := bs.visitorAddr(r)
+		clientIP := remoteAddr.String()
 
 		defer func() {
-			delete(bs.reqIdChanMap, requestId)
+			bs.reqIdChanMap.Unregister(requestId)
 			bs.logger.Info("cleaned up resources for request", zap.String("req_id", requestId))
 		}()
 
@@ -160,14 +829,75 @@ func (bs *BoreServer) StartBoreServer() error {
 
 		reqLogger.Info("new incoming request", zap.String("method", r.Method), zap.String("host", r.Host), zap.String("path", r.URL.Path))
 
-		app, ok := bs.apps[appId]
+		app, ok := bs.apps.Lookup(appId)
 		if !ok {
 			reqLogger.Error("No app found!")
 			http.Error(w, "No app found!", http.StatusBadRequest)
 			return
 		}
 
-		bs.reqIdChanMap[requestId] = make(chan *borepb.Response)
+		app.requests.Add(1)
+
+		if app.tcpListener != nil {
+			reqLogger.Warn("http request for a tcp tunnel")
+			renderErrorPage(w, http.StatusBadRequest, fmt.Sprintf("This tunnel forwards raw TCP on port %d, not HTTP.", app.tcpPort))
+			return
+		}
+
+		if reason := app.ipFilter.check(remoteAddr); reason != "" {
+			bs.reportBlocked(app, &borepb.Blocked{
+				Id:         requestId,
+				RemoteAddr: remoteAddr.String(),
+				Method:     r.Method,
+				Path:       r.URL.RequestURI(),
+				Reason:     reason,
+			}, reqLogger)
+			renderErrorPage(w, http.StatusForbidden, "Your IP address is not allowed to access this tunnel.")
+			return
+		}
+
+		release, limit, retryAfter := bs.admit(app, remoteAddr, reqLogger)
+		if release == nil {
+			renderTooManyRequests(w, limit, retryAfter)
+			return
+		}
+		defer release()
+
+		if !app.authorize(r) {
+			reqLogger.Warn("visitor failed edge auth")
+			app.challenge(w)
+			return
+		}
+
+		if app.conn() == nil {
+			reqLogger.Warn("app is detached, waiting for client to resume")
+			renderErrorPage(w, http.StatusBadGateway, "This tunnel is reconnecting to bore. Please retry shortly.")
+			return
+		}
+
+		if bs.maxBodySize > 0 {
+			if r.ContentLength > bs.maxBodySize {
+				bs.limitExceeded(limitBodySize, reqLogger)
+				bs.renderBodyTooLarge(w)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, bs.maxBodySize)
+		}
+
+		pending := &pendingRequest{
+			responses:    make(chan *borepb.Response, responseBufferSize),
+			done:         make(chan struct{}),
+			bodyTooLarge: make(chan struct{}),
+			uploaded:     make(chan struct{}),
+		}
+		bs.reqIdChanMap.Register(requestId, pending)
+
+		var uploads sync.WaitGroup
+		defer uploads.Wait()
+		defer close(pending.done)
+
+		stream := app.streams.Open(requestId)
+		defer stream.Close()
 
 		hopByHopHeaders := []string{
 			"Connection",
@@ -180,13 +910,6 @@ func (bs *BoreServer) StartBoreServer() error {
 			"Trailer",
 		}
 
-		bodyBytes, err := io.ReadAll(r.Body)
-		if err != nil {
-			reqLogger.Error("Error reading request body", zap.Error(err))
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
-			return
-		}
-
 		cookies := ""
 		for _, cookie := range r.Cookies() {
 			cookies += cookie.String() + "; "
@@ -195,62 +918,130 @@ func (bs *BoreServer) StartBoreServer() error {
 		reqLogger.Debug("finished parsing cookies", zap.Any("cookies", cookies))
 
 		headersParsed := make(map[string]string)
-		headersParsed["X-Forwarded-For"] = clientIP
 		for headerName, headerValues := range r.Header {
 			if !slices.Contains(hopByHopHeaders, headerName) {
 				headersParsed[headerName] = strings.Join(headerValues, ",")
 			}
 		}
 
+		// canonical keys, so they replace whatever the visitor sent
+		headersParsed["X-Forwarded-For"] = clientIP
+		headersParsed["X-Real-Ip"] = clientIP
+
+		// The visitor's credentials were for the tunnel, not the upstream.
+		if app.hasEdgeAuth() {
+			delete(headersParsed, "Authorization")
+		}
+
 		reqLogger.Debug("finished parsing headers", zap.Any("headers", headersParsed))
 
 		req := &borepb.Request{
 			Id:        requestId,
 			Method:    r.Method,
 			Path:      r.RequestURI,
-			Body:      bodyBytes,
 			Cookies:   cookies,
 			Headers:   headersParsed,
 			Timestamp: time.Now().UnixMilli(),
 		}
 
-		reqBytes, err := proto.Marshal(req)
-		if err != nil {
-			reqLogger.Error("failed to marshal request", zap.Error(err))
+		if websocket.IsWebSocketUpgrade(r) && app.supports(protocol.FeatureWebSocket) {
+			bs.proxyWebSocket(w, r, app, req, pending, stream, reqLogger)
 			return
 		}
 
-		app.wsMutex.Lock()
-		err = app.wsConn.WriteMessage(websocket.BinaryMessage, reqBytes)
-		app.wsMutex.Unlock()
+		streamBody := r.ContentLength < 0 || r.ContentLength > bodyChunkSize
+		if streamBody {
+			req.Frame = borepb.FrameType_FRAME_START
+		} else {
+			bodyBytes, err := io.ReadAll(r.Body)
+			if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+				bs.limitExceeded(limitBodySize, reqLogger)
+				bs.renderBodyTooLarge(w)
+				return
+			}
+			if err != nil {
+				reqLogger.Error("Error reading request body", zap.Error(err))
+				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				return
+			}
+			req.Body = bodyBytes
+		}
+
+		disconnected, err := bs.sendRequest(app, req)
 		if err != nil {
-			reqLogger.Error("failed to write reqBytes to ws", zap.Error(err))
+			reqLogger.Error("failed to write request to ws", zap.Error(err))
+			renderErrorPage(w, http.StatusBadGateway, "Could not forward the request to the bore client.")
 			return
 		}
 
-		response := <-bs.reqIdChanMap[requestId]
-		reqLogger.Info("received response", zap.Int32("status_code", response.StatusCode), zap.Any("headers", response.Headers))
-
-		for headerName, headerValues := range response.Headers {
-			w.Header().Add(headerName, headerValues)
+		if streamBody {
+			uploads.Add(1)
+			go func() {
+				defer uploads.Done()
+				defer close(pending.uploaded)
+				err := bs.streamRequestBody(stream, r.Body, reqLogger)
+				if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+					bs.limitExceeded(limitBodySize, reqLogger)
+					bs.cancelRequest(app, requestId, "request body too large", reqLogger)
+					close(pending.bodyTooLarge)
+				}
+			}()
+		} else {
+			close(pending.uploaded)
 		}
 
-		w.WriteHeader(int(response.StatusCode))
+		bs.forwardResponse(w, r, app, pending, stream, disconnected, reqLogger)
+	}
 
-		size, err := w.Write(response.Body)
-		if err != nil {
-			reqLogger.Error("failed to forward response to bore client", zap.Error(err))
+	visitors := bs.metrics.instrument(http.HandlerFunc(handleVisitor))
+
+	// /ws is only the bore client endpoint on the server's own host, so
+	// tunneled apps can use the path too. Whether an app is registered for
+	// the host doesn't matter, or claiming the server host's label would hand
+	// other clients' hellos to that app.
+	router.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if !bs.isServerHost(r.Host) {
+			visitors.ServeHTTP(w, r)
 			return
 		}
 
-		reqLogger.Info("response forwarded to bore client", zap.Int("res_size", size))
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleClient(w, r)
 	})
+	router.Handle("/*", visitors)
+
+	return router
+}
+
+func (bs *BoreServer) StartBoreServer() error {
+	if bs.adminAddr != "" {
+		go func() {
+			err := bs.startAdminServer()
+			if err != nil {
+				bs.logger.Error("admin server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	router := bs.routes()
+
+	if bs.terminatesTLS() {
+		return bs.serveTLS(router)
+	}
 
 	for range maxRetries {
 		netListener, err := net.Listen("tcp", fmt.Sprintf(":%d", bs.port))
 		if err == nil {
 			bs.logger.Info(fmt.Sprintf("Bore server is running on http://localhost:%d/", bs.port))
-			return http.Serve(netListener, router)
+
+			srv := &http.Server{Handler: router}
+			return bs.serve(srv, func() error {
+				return srv.Serve(netListener)
+			})
 		}
 
 		bs.port++
@@ -276,11 +1067,50 @@ func NewBoreServer(boreCfg *BoreServerCfg) *BoreServer {
 	h.TokenLength = 5
 	h.TokenChars = "abcdefghijklmnopqrstuvwxyz0123456789"
 
-	return &BoreServer{
-		reqIdChanMap: make(map[string]chan *borepb.Response),
-		apps:         make(map[string]App),
-		haikunator:   h,
-		logger:       logger,
-		port:         boreCfg.Port,
+	bs := &BoreServer{
+		reqIdChanMap:       NewRegistry[string, *pendingRequest](),
+		apps:               NewRegistry[string, *App](),
+		resumeTokens:       NewRegistry[string, string](),
+		haikunator:         h,
+		logger:             logger,
+		port:               boreCfg.Port,
+		resumeGracePeriod:  boreCfg.ResumeGracePeriod,
+		requestTimeout:     boreCfg.RequestTimeout,
+		tcpPortMin:         boreCfg.TCPPortMin,
+		tcpPortMax:         boreCfg.TCPPortMax,
+		version:            boreCfg.Version,
+		reservedSubdomains: newReservedSubdomains(boreCfg.ReservedSubdomains),
+		tokens:             newTokenStore(boreCfg.Tokens),
+		rateLimit:          boreCfg.RateLimit,
+		rateBurst:          boreCfg.RateBurst,
+		visitorLimiter:     newVisitorLimiter(boreCfg.VisitorRateLimit, boreCfg.VisitorRateBurst),
+		maxBodySize:        boreCfg.MaxBodySize,
+		maxInFlight:        boreCfg.MaxInFlight,
+		limitHits:          newLimitHits(),
+		adminAddr:          boreCfg.AdminAddr,
+		adminToken:         boreCfg.AdminToken,
+		drainTimeout:       boreCfg.DrainTimeout,
+		tlsCert:            boreCfg.TLSCert,
+		tlsKey:             boreCfg.TLSKey,
+		acme:               boreCfg.ACME,
+		acmeEmail:          boreCfg.ACMEEmail,
+		acmeDirectory:      boreCfg.ACMEDirectory,
+		acmeCacheDir:       boreCfg.ACMECacheDir,
+		httpsPort:          boreCfg.HTTPSPort,
+		httpPort:           boreCfg.HTTPPort,
+		customDomains:      NewRegistry[string, string](),
+		baseDomains:        parseBaseDomains(boreCfg.Domains),
+		host:               normalizeDomain(boreCfg.Host),
+		scheme:             boreCfg.Scheme,
+		trustedProxies:     boreCfg.TrustedProxies,
+	}
+	if bs.terminatesTLS() || bs.scheme == "" {
+		bs.scheme = "https"
 	}
+	if label := bs.hostLabel(); label != "" {
+		bs.reservedSubdomains.Register(label, struct{}{})
+	}
+	bs.metrics = newMetrics(bs)
+
+	return bs
 }
@@ -0,0 +1,363 @@
+package server
+
+import (
+	borepb "bore/borepb"
+	"bore/internal/client"
+	"bore/internal/protocol"
+	"bore/internal/traffik"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestServer creates a bore server that logs to a temporary directory,
+// without serving anything.
+func newTestServer(t *testing.T, cfg *BoreServerCfg) *BoreServer {
+	t.Helper()
+
+	cfg.LogFile = filepath.Join(t.TempDir(), "bore.log")
+	return NewBoreServer(cfg)
+}
+
+// startTestServer runs the bore server's routes on a local listener, and
+// points bore clients at it.
+func startTestServer(t *testing.T, cfg *BoreServerCfg) (*BoreServer, *httptest.Server) {
+	t.Helper()
+
+	if cfg.ResumeGracePeriod == 0 {
+		cfg.ResumeGracePeriod = time.Second
+	}
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = 30 * time.Second
+	}
+
+	bs := newTestServer(t, cfg)
+	srv := httptest.NewServer(bs.routes())
+	t.Cleanup(srv.Close)
+
+	client.BoreServerHost = srv.Listener.Addr().String()
+	client.WSScheme = "ws"
+
+	return bs, srv
+}
+
+// startTestTunnel connects a bore client for upstream and returns its app ID.
+func startTestTunnel(t *testing.T, upstream string) string {
+	t.Helper()
+
+	bc := client.NewBoreClient(&client.BoreClientConfig{
+		UpstreamURL: upstream,
+		Traffik:     traffik.NewLogger(),
+		Version:     "test",
+		NoTui:       true,
+		Concurrency: 16,
+	})
+
+	registered := make(chan error, 1)
+	go func() {
+		registered <- bc.RegisterApp()
+	}()
+
+	select {
+	case <-bc.Ready:
+	case err := <-registered:
+		t.Fatalf("bore client failed to register: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("bore client did not connect")
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		bc.Shutdown(ctx)
+		<-registered
+	})
+
+	return bc.AppId()
+}
+
+// TestTunnelsUnderLoad sends concurrent small and streamed requests through
+// several tunnels at once. It is most useful with -race.
+func TestTunnelsUnderLoad(t *testing.T) {
+	// the bore client logs relative to the working directory
+	t.Chdir(t.TempDir())
+
+	_, srv := startTestServer(t, &BoreServerCfg{})
+
+	const (
+		tunnels  = 4
+		visitors = 8
+		requests = 20
+	)
+
+	appIds := make([]string, tunnels)
+	for i := range tunnels {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Upstream", fmt.Sprint(i))
+			w.Header().Set("X-Path", r.URL.Path)
+
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		}))
+		t.Cleanup(upstream.Close)
+
+		appIds[i] = startTestTunnel(t, upstream.URL)
+	}
+
+	serverURL, _ := url.Parse(srv.URL)
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, tunnels*visitors*requests)
+
+	for tunnel, appId := range appIds {
+		for visitor := range visitors {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for i := range requests {
+					// every other request is large enough to be streamed
+					size := 100
+					if i%2 == 1 {
+						size = 3*bodyChunkSize + i
+					}
+					body := bytes.Repeat([]byte{byte('a' + visitor)}, size)
+					path := fmt.Sprintf("/visitor/%d/request/%d", visitor, i)
+
+					req, _ := http.NewRequest(http.MethodPost, serverURL.JoinPath(path).String(), bytes.NewReader(body))
+					req.Host = appId + ".localhost"
+
+					res, err := httpClient.Do(req)
+					if err != nil {
+						errs <- fmt.Errorf("%s%s: %w", appId, path, err)
+						continue
+					}
+					got, err := io.ReadAll(res.Body)
+					res.Body.Close()
+
+					switch {
+					case err != nil:
+						errs <- fmt.Errorf("%s%s: reading response: %w", appId, path, err)
+					case res.StatusCode != http.StatusOK:
+						errs <- fmt.Errorf("%s%s: status %d: %s", appId, path, res.StatusCode, got)
+					case res.Header.Get("X-Upstream") != fmt.Sprint(tunnel):
+						errs <- fmt.Errorf("%s%s: answered by upstream %s", appId, path, res.Header.Get("X-Upstream"))
+					case res.Header.Get("X-Path") != path:
+						errs <- fmt.Errorf("%s%s: upstream saw path %s", appId, path, res.Header.Get("X-Path"))
+					case !bytes.Equal(got, body):
+						errs <- fmt.Errorf("%s%s: sent %d bytes, got %d back", appId, path, len(body), len(got))
+					}
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// visit sends a request for appId's host to the bore server.
+func visit(t *testing.T, srv *httptest.Server, appId string, method string, path string, body io.Reader) (*http.Response, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, srv.URL+path, body)
+	req.Host = appId + ".localhost"
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	got, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res, got
+}
+
+func TestSlowUploadOutlastsRequestTimeout(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	_, srv := startTestServer(t, &BoreServerCfg{RequestTimeout: 300 * time.Millisecond})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		fmt.Fprint(w, n)
+	}))
+	t.Cleanup(upstream.Close)
+	appId := startTestTunnel(t, upstream.URL)
+
+	// a body of unknown length, sent over longer than the request timeout
+	body, writer := io.Pipe()
+	go func() {
+		for range 5 {
+			writer.Write(bytes.Repeat([]byte("a"), bodyChunkSize))
+			time.Sleep(200 * time.Millisecond)
+		}
+		writer.Close()
+	}()
+
+	res, got := visit(t, srv, appId, http.MethodPost, "/upload", body)
+	if res.StatusCode != http.StatusOK || string(got) != fmt.Sprint(5*bodyChunkSize) {
+		t.Fatalf("want 200 with %d bytes uploaded, got %d: %s", 5*bodyChunkSize, res.StatusCode, got)
+	}
+}
+
+func TestRequestTimeoutAfterUpload(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	_, srv := startTestServer(t, &BoreServerCfg{RequestTimeout: 300 * time.Millisecond})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	appId := startTestTunnel(t, upstream.URL)
+
+	res, _ := visit(t, srv, appId, http.MethodPost, "/slow", bytes.NewReader(make([]byte, 2*bodyChunkSize)))
+	if res.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("want 504 for an upstream slower than the request timeout, got %d", res.StatusCode)
+	}
+}
+
+func TestVisitorCannotSpoofForwardedAddress(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// no trusted proxies, so the visitor's own X-Real-IP must be ignored
+	_, srv := startTestServer(t, &BoreServerCfg{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+	}))
+	t.Cleanup(upstream.Close)
+	appId := startTestTunnel(t, upstream.URL)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+	req.Host = appId + ".localhost"
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	got, _ := io.ReadAll(res.Body)
+
+	if string(got) != "127.0.0.1 127.0.0.1" {
+		t.Fatalf("want the upstream to see the visitor's real address, got %q", got)
+	}
+}
+
+func TestOutdatedClientIsRefusedBeforeUpgrade(t *testing.T) {
+	_, srv := startTestServer(t, &BoreServerCfg{})
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{"no protocol header", "", "This bore client is out of date. Please upgrade bore."},
+		{"invalid version", "two", `This bore client sent an invalid protocol version "two". Please upgrade bore.`},
+		{"unsupported version", "2", "This bore client is out of date: protocol v2 is no longer supported, v3 or newer is required. Please upgrade bore."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+			if tt.version != "" {
+				req.Header.Set(protocol.Header, tt.version)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			got, _ := io.ReadAll(res.Body)
+
+			if res.StatusCode != http.StatusUpgradeRequired || strings.TrimSpace(string(got)) != tt.want {
+				t.Fatalf("want 426 %q, got %d %q", tt.want, res.StatusCode, got)
+			}
+		})
+	}
+}
+
+func TestDetachedSubdomainCanBeTakenOverWithItsToken(t *testing.T) {
+	bs, _ := startTestServer(t, &BoreServerCfg{
+		Tokens:            []TokenConfig{tokenConfig("alice", "alice-secret"), tokenConfig("bob", "bob-secret")},
+		ResumeGracePeriod: time.Hour,
+	})
+	alice, _ := bs.tokens.authenticate("alice-secret")
+	bob, _ := bs.tokens.authenticate("bob-secret")
+	hello := &borepb.Hello{Subdomain: "mine"}
+
+	app, err := bs.registerApp(hello, alice, "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the client went away without a shutdown message
+	bs.detachApp(app, nil)
+
+	_, err = bs.registerApp(hello, bob, "localhost")
+	if err == nil {
+		t.Fatal("another token took over a detached subdomain")
+	}
+
+	takenOver, err := bs.registerApp(hello, alice, "localhost")
+	if err != nil {
+		t.Fatalf("want the same token to take over its detached subdomain, got %v", err)
+	}
+	if registered, _ := bs.apps.Lookup("mine"); registered != takenOver {
+		t.Fatal("the detached app is still registered")
+	}
+	if _, ok := bs.resumeApp(app.resumeToken, alice); ok {
+		t.Fatal("the detached app can still be resumed after it was taken over")
+	}
+}
+
+func TestRenderClientError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        string
+		statusCode int
+		message    string
+	}{
+		{"shutting down", protocol.ErrorShuttingDown, http.StatusServiceUnavailable, "This tunnel is shutting down."},
+		{"upstream error", "upstream refused the connection", http.StatusBadGateway, "The bore client is running, but the upstream refused the connection."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			renderClientError(w, &borepb.Response{Error: tt.err}, zap.NewNop())
+
+			if w.Code != tt.statusCode || !strings.Contains(w.Body.String(), tt.message) {
+				t.Fatalf("want %d with %q, got %d: %s", tt.statusCode, tt.message, w.Code, w.Body.String())
+			}
+		})
+	}
+}
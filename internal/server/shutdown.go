@@ -0,0 +1,78 @@
+package server
+
+import (
+	borepb "bore/borepb"
+	"context"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// serve runs srv until it fails or the process is asked to stop, in which
+// case the server is drained before serve returns.
+func (bs *BoreServer) serve(srv *http.Server, listen func() error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+
+		<-ctx.Done()
+		bs.shutdown(srv)
+	}()
+
+	err := listen()
+	if err != http.ErrServerClosed {
+		return err
+	}
+
+	<-drained
+	return nil
+}
+
+// shutdown stops accepting new tunnels, gives in-flight requests until the
+// drain timeout to finish, then tells every client to reconnect, so they
+// come back once the server is up again.
+func (bs *BoreServer) shutdown(srv *http.Server) {
+	bs.draining.Store(true)
+	bs.logger.Info("shutting down, draining in-flight requests", zap.Int("pending_requests", bs.reqIdChanMap.Len()), zap.Duration("drain_timeout", bs.drainTimeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), bs.drainTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		bs.logger.Warn("drain timeout passed with requests in flight", zap.Error(err), zap.Int("pending_requests", bs.reqIdChanMap.Len()))
+	}
+
+	bs.apps.Range(func(_ string, app *App) bool {
+		bs.sendShutdown(app, "bore server is restarting")
+		return true
+	})
+
+	bs.logger.Info("bore server stopped")
+}
+
+func (bs *BoreServer) sendShutdown(app *App, reason string) {
+	app.closeTCPListener()
+
+	conn := app.conn()
+	if conn == nil {
+		return
+	}
+
+	_, err := bs.send(app, &borepb.Envelope{
+		Message: &borepb.Envelope_Shutdown{Shutdown: &borepb.Shutdown{Reason: reason}},
+	})
+	if err != nil {
+		bs.logger.Debug("failed to send shutdown to bore client", zap.Error(err), zap.String("app_id", app.id))
+	}
+
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, reason), time.Now().Add(time.Second))
+	conn.Close()
+}
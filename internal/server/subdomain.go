@@ -0,0 +1,41 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultReservedSubdomains can't be claimed by clients, on top of any the
+// server is configured with.
+var defaultReservedSubdomains = []string{"www", "api", "admin", "app", "ws", "mail", "status", "docs"}
+
+var subdomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// validateSubdomain checks that a subdomain requested by a client is a
+// single DNS label the server is willing to hand out to tok.
+func (bs *BoreServer) validateSubdomain(subdomain string, tok *token) error {
+	if !subdomainPattern.MatchString(subdomain) {
+		return fmt.Errorf("subdomain %q is not valid: use 1-63 lowercase letters, digits and hyphens, not starting or ending with a hyphen", subdomain)
+	}
+
+	if _, reserved := bs.reservedSubdomains.Lookup(subdomain); reserved || !bs.tokens.canClaim(tok, subdomain) {
+		return fmt.Errorf("subdomain %q is reserved", subdomain)
+	}
+
+	return nil
+}
+
+// newReservedSubdomains is a registry rather than a plain set since
+// operators can reserve and release subdomains through the admin API.
+func newReservedSubdomains(extra []string) *Registry[string, struct{}] {
+	reserved := NewRegistry[string, struct{}]()
+	for _, subdomain := range append(defaultReservedSubdomains, extra...) {
+		subdomain = strings.ToLower(strings.TrimSpace(subdomain))
+		if subdomain != "" {
+			reserved.Register(subdomain, struct{}{})
+		}
+	}
+
+	return reserved
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSubdomain(t *testing.T) {
+	owned := tokenConfig("alice", "alice-secret")
+	owned.Subdomains = []string{"shop"}
+	bs := newTestServer(t, &BoreServerCfg{
+		ReservedSubdomains: []string{" Blog "},
+		Tokens:             []TokenConfig{owned, tokenConfig("bob", "bob-secret")},
+	})
+	alice, _ := bs.tokens.authenticate("alice-secret")
+	bob, _ := bs.tokens.authenticate("bob-secret")
+
+	tests := []struct {
+		name      string
+		subdomain string
+		tok       *token
+		wantErr   string
+	}{
+		{"simple", "my-app", bob, ""},
+		{"digits", "app2", bob, ""},
+		{"single character", "a", bob, ""},
+		{"longest label", strings.Repeat("a", 63), bob, ""},
+		{"too long", strings.Repeat("a", 64), bob, "is not valid"},
+		{"empty", "", bob, "is not valid"},
+		{"uppercase", "MyApp", bob, "is not valid"},
+		{"leading hyphen", "-app", bob, "is not valid"},
+		{"trailing hyphen", "app-", bob, "is not valid"},
+		{"dot", "my.app", bob, "is not valid"},
+		{"underscore", "my_app", bob, "is not valid"},
+		{"reserved by default", "admin", bob, "is reserved"},
+		{"reserved by config", "blog", bob, "is reserved"},
+		{"owned by the token", "shop", alice, ""},
+		{"owned by another token", "shop", bob, "is reserved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bs.validateSubdomain(tt.subdomain, tt.tok)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("want %q accepted, got %v", tt.subdomain, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want an error containing %q for %q, got %v", tt.wantErr, tt.subdomain, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	borepb "bore/borepb"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var errNoTCPPorts = errors.New("no free tcp ports left in range")
+
+// listenTCP binds app to a free public port from the configured range,
+// starting at a random port so freed ports aren't immediately reused.
+func (bs *BoreServer) listenTCP(app *App) error {
+	if bs.tcpPortMin == 0 {
+		return errors.New("tcp tunnels are not enabled on this bore server")
+	}
+
+	span := bs.tcpPortMax - bs.tcpPortMin + 1
+	start := rand.N(span)
+
+	for i := range span {
+		port := bs.tcpPortMin + (start+i)%span
+
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			continue
+		}
+
+		app.tcpListener = listener
+		app.tcpPort = port
+
+		return nil
+	}
+
+	return errNoTCPPorts
+}
+
+func (app *App) closeTCPListener() {
+	if app.tcpListener != nil {
+		app.tcpListener.Close()
+	}
+}
+
+func (bs *BoreServer) serveTCP(app *App) {
+	appLogger := bs.logger.With(zap.String("app_id", app.id), zap.Int("tcp_port", app.tcpPort))
+	appLogger.Info("listening for tcp connections")
+
+	for {
+		conn, err := app.tcpListener.Accept()
+		if err != nil {
+			appLogger.Info("stopped listening for tcp connections", zap.Error(err))
+			return
+		}
+
+		go bs.handleTCPConn(app, conn, appLogger)
+	}
+}
+
+// handleTCPConn relays a visitor's raw byte stream to the bore client over a
+// new stream, until either side closes it.
+func (bs *BoreServer) handleTCPConn(app *App, conn net.Conn, appLogger *zap.Logger) {
+	defer conn.Close()
+
+	requestId := uuid.New().String()
+	remoteIP, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+
+	connLogger := appLogger.With(zap.String("req_id", requestId), zap.String("client_ip", remoteIP))
+	connLogger.Info("new tcp connection")
+	app.requests.Add(1)
+
+	remoteAddr, _ := netip.ParseAddr(remoteIP)
+	if reason := app.ipFilter.check(remoteAddr); reason != "" {
+		bs.reportBlocked(app, &borepb.Blocked{
+			Id:         requestId,
+			RemoteAddr: remoteIP,
+			Reason:     reason,
+		}, connLogger)
+		return
+	}
+
+	release, _, _ := bs.admit(app, remoteAddr, connLogger)
+	if release == nil {
+		return
+	}
+	defer release()
+
+	stream := app.streams.Open(requestId)
+	defer func() {
+		stream.Close()
+		connLogger.Info("closed tcp connection")
+	}()
+
+	_, err := bs.sendRequest(app, &borepb.Request{
+		Id:      requestId,
+		Frame:   borepb.FrameType_FRAME_START,
+		Headers: map[string]string{"X-Forwarded-For": remoteIP},
+	})
+	if err != nil {
+		connLogger.Warn("could not forward tcp connection to bore client", zap.Error(err))
+		return
+	}
+
+	go func() {
+		_, err := io.Copy(stream, conn)
+		if err != nil {
+			connLogger.Debug("failed to forward tcp data to bore client", zap.Error(err))
+		}
+		stream.CloseWrite()
+	}()
+
+	_, err = io.Copy(conn, stream)
+	if err != nil {
+		connLogger.Warn("tcp stream failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certificates are checked for renewal on disk at most this often
+const certReloadInterval = time.Minute
+
+// certFile serves a certificate from disk, picking up renewals, e.g. by
+// certbot, without a restart.
+type certFile struct {
+	certPath  string
+	keyPath   string
+	mutex     sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func loadCertFile(certPath string, keyPath string) (*certFile, error) {
+	f := &certFile{certPath: certPath, keyPath: keyPath}
+
+	_, err := f.get(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *certFile) get(now time.Time) (*tls.Certificate, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.cert != nil && now.Sub(f.lastCheck) < certReloadInterval {
+		return f.cert, nil
+	}
+	f.lastCheck = now
+
+	info, err := os.Stat(f.certPath)
+	if err != nil {
+		return f.cert, err
+	}
+	if f.cert != nil && info.ModTime().Equal(f.modTime) {
+		return f.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.certPath, f.keyPath)
+	if err != nil {
+		return f.cert, err
+	}
+
+	f.cert = &cert
+	f.modTime = info.ModTime()
+
+	return f.cert, nil
+}
+
+// newCertManager obtains and renews certificates for verified custom
+// domains and, without a wildcard certificate, for the server's own hosts
+// and each app's host on its first visit.
+func (bs *BoreServer) newCertManager() *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(bs.acmeCacheDir),
+		Email:      bs.acmeEmail,
+		HostPolicy: bs.certHostPolicy,
+		Client: &acme.Client{
+			DirectoryURL: bs.acmeDirectory,
+		},
+	}
+}
+
+// certHostPolicy only allows certificates for hosts the server answers on:
+// custom domains claimed by an app, the server's own hosts, and the hosts of
+// registered apps. Visitors can't burn through the CA's rate limits with
+// random names.
+func (bs *BoreServer) certHostPolicy(_ context.Context, host string) error {
+	host = normalizeDomain(host)
+	if _, ok := bs.customDomains.Lookup(host); ok {
+		return nil
+	}
+	if host == bs.host {
+		return nil
+	}
+
+	_, label, ok := bs.matchBaseDomain(host)
+	if ok && label == "" {
+		return nil
+	}
+	if appId, ok := bs.appIdForHost(host); ok {
+		if _, ok := bs.apps.Lookup(appId); ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no certificate for %q: not a bore host or a registered app", host)
+}
+
+// getCertificate picks the ACME certificate for custom domains, and the
+// wildcard certificate for everything else. Without a wildcard certificate,
+// every host gets its own from ACME.
+func (bs *BoreServer) getCertificate(certs *certFile, certManager *autocert.Manager) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if certManager != nil {
+			if _, ok := bs.customDomains.Lookup(normalizeDomain(hello.ServerName)); ok {
+				return certManager.GetCertificate(hello)
+			}
+		}
+		if certs == nil {
+			return certManager.GetCertificate(hello)
+		}
+
+		cert, err := certs.get(time.Now())
+		if err != nil {
+			bs.logger.Error("failed to reload tls certificate", zap.Error(err))
+		}
+
+		return cert, nil
+	}
+}
+
+// terminatesTLS reports whether the server serves HTTPS itself, with a
+// wildcard certificate, ACME, or both.
+func (bs *BoreServer) terminatesTLS() bool {
+	return bs.tlsCert != "" || bs.acme
+}
+
+// serveTLS terminates TLS itself, redirecting plain HTTP to HTTPS apart from
+// ACME HTTP-01 challenges.
+func (bs *BoreServer) serveTLS(router http.Handler) error {
+	var certs *certFile
+	if bs.tlsCert != "" {
+		var err error
+		certs, err = loadCertFile(bs.tlsCert, bs.tlsKey)
+		if err != nil {
+			return fmt.Errorf("failed to load tls certificate: %w", err)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	var redirectHandler http.Handler = http.HandlerFunc(bs.redirectToHTTPS)
+	var certManager *autocert.Manager
+	if bs.acme {
+		certManager = bs.newCertManager()
+		redirectHandler = certManager.HTTPHandler(redirectHandler)
+		tlsConfig.NextProtos = append(tlsConfig.NextProtos, acme.ALPNProto)
+	}
+	tlsConfig.GetCertificate = bs.getCertificate(certs, certManager)
+
+	redirect := &http.Server{
+		Addr:    fmt.Sprintf(":%d", bs.httpPort),
+		Handler: redirectHandler,
+	}
+	go func() {
+		err := redirect.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			bs.logger.Error("http redirect server stopped", zap.Error(err))
+		}
+	}()
+
+	srv := &http.Server{
+		Addr:      fmt.Sprintf(":%d", bs.httpsPort),
+		Handler:   realIP(router),
+		TLSConfig: tlsConfig,
+	}
+	srv.RegisterOnShutdown(func() {
+		redirect.Close()
+	})
+
+	bs.logger.Info("Bore server is running with tls", zap.Int("https_port", bs.httpsPort), zap.Int("http_port", bs.httpPort), zap.Strings("domains", bs.baseDomainList()), zap.Bool("acme", bs.acme))
+
+	return bs.serve(srv, func() error {
+		return srv.ListenAndServeTLS("", "")
+	})
+}
+
+// redirectToHTTPS sends plain HTTP visitors to the same URL on the HTTPS
+// port.
+func (bs *BoreServer) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if bs.httpsPort != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(bs.httpsPort))
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
+
+// realIP sets X-Real-IP from the connection, like nginx does, when there is
+// no proxy in front of the server. Visitors can't be trusted to set it.
+func realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		r.Header.Set("X-Real-IP", host)
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCertHostPolicy(t *testing.T) {
+	bs := newTestServer(t, &BoreServerCfg{
+		Domains: []string{"trybore.com"},
+		Host:    "app.trybore.com",
+		ACME:    true,
+	})
+	bs.apps.Register("happy-app", &App{id: "happy-app"})
+	bs.customDomains.Register("shop.example.com", "happy-app")
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"trybore.com", true},
+		{"app.trybore.com", true},
+		{"happy-app.trybore.com", true},
+		{"HAPPY-APP.trybore.com", true},
+		{"shop.example.com", true},
+		{"unknown-app.trybore.com", false},
+		{"a.happy-app.trybore.com", false},
+		{"example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := bs.certHostPolicy(context.Background(), tt.host)
+			if (err == nil) != tt.allowed {
+				t.Fatalf("want allowed=%v, got %v", tt.allowed, err)
+			}
+		})
+	}
+}
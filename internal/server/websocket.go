@@ -0,0 +1,134 @@
+package server
+
+import (
+	borepb "bore/borepb"
+	"bore/internal/mux"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// proxyWebSocket asks the bore client to open a websocket to the upstream and,
+// once the upstream accepts, upgrades the visitor and relays messages both
+// ways over the request's stream.
+func (bs *BoreServer) proxyWebSocket(w http.ResponseWriter, r *http.Request, app *App, req *borepb.Request, pending *pendingRequest, stream *mux.Stream, reqLogger *zap.Logger) {
+	req.Frame = borepb.FrameType_FRAME_START
+	req.Upgrade = true
+
+	disconnected, err := bs.sendRequest(app, req)
+	if err != nil {
+		reqLogger.Error("failed to write upgrade request to ws", zap.Error(err))
+		renderErrorPage(w, http.StatusBadGateway, "Could not forward the request to the bore client.")
+		return
+	}
+
+	timeout := time.NewTimer(bs.requestTimeout)
+	defer timeout.Stop()
+
+	var response *borepb.Response
+
+	select {
+	case response = <-pending.responses:
+	case <-disconnected:
+		reqLogger.Warn("bore client disconnected during websocket handshake")
+		renderErrorPage(w, http.StatusBadGateway, "The bore client disconnected before it could respond.")
+		return
+	case <-timeout.C:
+		reqLogger.Warn("timed out waiting for websocket handshake", zap.Duration("timeout", bs.requestTimeout))
+		bs.cancelRequest(app, req.Id, fmt.Sprintf("no response within %s", bs.requestTimeout), reqLogger)
+		renderErrorPage(w, http.StatusGatewayTimeout, fmt.Sprintf("The bore client did not respond within %s.", bs.requestTimeout))
+		return
+	case <-r.Context().Done():
+		reqLogger.Info("visitor went away during websocket handshake", zap.Error(r.Context().Err()))
+		bs.cancelRequest(app, req.Id, "visitor disconnected", reqLogger)
+		return
+	}
+
+	if response.Error != "" {
+		renderClientError(w, response, reqLogger)
+		return
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		reqLogger.Info("upstream rejected websocket handshake", zap.Int32("status_code", response.StatusCode))
+
+		for headerName, headerValues := range response.Headers {
+			w.Header().Add(headerName, headerValues)
+		}
+
+		w.WriteHeader(int(response.StatusCode))
+		w.Write(response.Body)
+		return
+	}
+
+	responseHeader := http.Header{}
+	for headerName, headerValues := range response.Headers {
+		responseHeader.Set(headerName, headerValues)
+	}
+
+	// the upstream has already checked the origin it was forwarded
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+	}
+
+	visitorConn, err := upgrader.Upgrade(w, r, responseHeader)
+	if err != nil {
+		reqLogger.Error("failed to upgrade visitor connection to WS", zap.Error(err))
+		stream.CloseWrite()
+		return
+	}
+	defer visitorConn.Close()
+
+	reqLogger.Info("websocket passthrough established")
+
+	go func() {
+		defer stream.CloseWrite()
+
+		for {
+			messageType, data, err := visitorConn.ReadMessage()
+			if err != nil {
+				reqLogger.Debug("visitor websocket closed", zap.Error(err))
+				return
+			}
+
+			err = stream.WriteMessage(int32(messageType), data)
+			if err != nil {
+				reqLogger.Debug("failed to forward websocket message to bore client", zap.Error(err))
+				return
+			}
+		}
+	}()
+
+	for {
+		messageType, data, err := stream.ReadMessage()
+		if err == io.EOF {
+			reqLogger.Info("upstream closed websocket")
+			closeWebSocket(visitorConn, websocket.CloseNormalClosure)
+			return
+		}
+
+		if err != nil {
+			reqLogger.Warn("websocket stream failed", zap.Error(err))
+			closeWebSocket(visitorConn, websocket.CloseGoingAway)
+			return
+		}
+
+		err = visitorConn.WriteMessage(int(messageType), data)
+		if err != nil {
+			reqLogger.Debug("failed to write websocket message to visitor", zap.Error(err))
+			return
+		}
+	}
+}
+
+func closeWebSocket(conn *websocket.Conn, code int) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(5*time.Second))
+}
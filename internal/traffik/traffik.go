@@ -2,13 +2,17 @@ package traffik
 
 import (
 	borepb "bore/borepb"
-	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"google.golang.org/protobuf/proto"
 	"resty.dev/v3"
 )
 
@@ -16,11 +20,27 @@ type RequestID string
 
 const RequestIDKey RequestID = "bore-request-id"
 
+// bodies larger than this are only partially kept for the inspectors
+const maxLoggedBodySize = 4 * 1024 * 1024
+
 type Log struct {
 	RequestID string
 	Request   *borepb.Request
 	Response  *borepb.Response
 	Duration  int64
+	Cancelled bool
+}
+
+// snapshot copies the log, so it can be read while bodies are still being
+// recorded into the original. The caller must hold the logger's mutex.
+func (log *Log) snapshot() *Log {
+	return &Log{
+		RequestID: log.RequestID,
+		Request:   proto.Clone(log.Request).(*borepb.Request),
+		Response:  proto.Clone(log.Response).(*borepb.Response),
+		Duration:  log.Duration,
+		Cancelled: log.Cancelled,
+	}
 }
 
 type Logger struct {
@@ -39,10 +59,22 @@ func (l *Logger) LogRequest(req *resty.Request) {
 	// fmt.Println("Logging request:", requestID)
 
 	request := borepb.Request{
-		Method:  req.Method,
-		Path:    req.URL,
-		Headers: l.flattenHeaders(req.Header),
-		Body:    req.Body.([]byte),
+		Method:    req.Method,
+		Path:      req.URL,
+		Headers:   l.flattenHeaders(req.Header),
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	switch body := req.Body.(type) {
+	case []byte:
+		request.Body = body
+	case io.Reader:
+		req.SetBody(&bodyRecorder{
+			reader: body,
+			record: func(chunk []byte) {
+				l.recordBody(requestID, chunk, func(log *Log) *[]byte { return &log.Request.Body })
+			},
+		})
 	}
 
 	l.mutex.Lock()
@@ -69,15 +101,12 @@ func (l *Logger) LogResponse(res *resty.Response) {
 	}
 
 	if res.Body != nil {
-		bodyBytes, err := io.ReadAll(res.Body)
-		if err != nil {
-			// fmt.Println("Error reading response body:", err)
-			return
+		res.Body = &bodyRecorder{
+			reader: res.Body,
+			record: func(chunk []byte) {
+				l.recordBody(requestID, chunk, func(log *Log) *[]byte { return &log.Response.Body })
+			},
 		}
-
-		res.Body.Close()
-		res.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-		response.Body = bodyBytes
 	}
 
 	l.mutex.Lock()
@@ -93,13 +122,126 @@ func (l *Logger) LogResponse(res *resty.Response) {
 	l.logs[requestID].Duration = responseTimestamp - requestTimestamp
 }
 
+func (l *Logger) LogError(req *resty.Request, message string) {
+	requestID := req.Context().Value(RequestIDKey).(string)
+
+	responseTimestamp := time.Now().UnixMilli()
+	requestTimestamp := responseTimestamp
+	if !req.Time.IsZero() {
+		requestTimestamp = req.Time.UnixMilli()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.logs[requestID]; !ok {
+		return
+	}
+
+	l.logs[requestID].Request.Timestamp = requestTimestamp
+	l.logs[requestID].Response = &borepb.Response{
+		StatusCode: http.StatusBadGateway,
+		Timestamp:  responseTimestamp,
+		Error:      message,
+	}
+	l.logs[requestID].Duration = responseTimestamp - requestTimestamp
+}
+
+// LogCancelled marks a request the server cancelled, e.g. because the visitor
+// went away. A response that had already started is kept as it is.
+func (l *Logger) LogCancelled(requestID string, reason string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	log, ok := l.logs[requestID]
+	if !ok {
+		return
+	}
+
+	log.Cancelled = true
+
+	if log.Response.StatusCode == 0 {
+		responseTimestamp := time.Now().UnixMilli()
+
+		log.Response = &borepb.Response{
+			Timestamp: responseTimestamp,
+			Error:     reason,
+		}
+		if log.Request.Timestamp != 0 {
+			log.Duration = responseTimestamp - log.Request.Timestamp
+		}
+	}
+}
+
+// LogBlocked records a visitor the server turned away before anything was
+// forwarded to the client.
+func (l *Logger) LogBlocked(blocked *borepb.Blocked) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.logs[blocked.Id] = &Log{
+		RequestID: blocked.Id,
+		Request: &borepb.Request{
+			Method:    blocked.Method,
+			Path:      blocked.Path,
+			Headers:   map[string]string{"X-Forwarded-For": blocked.RemoteAddr},
+			Timestamp: blocked.Timestamp,
+		},
+		Response: &borepb.Response{
+			StatusCode: http.StatusForbidden,
+			Timestamp:  blocked.Timestamp,
+			Error:      "blocked by bore server: " + blocked.Reason,
+		},
+	}
+}
+
+// LogWebSocket records the handshake of a websocket passthrough, whose
+// messages are relayed directly and not kept.
+func (l *Logger) LogWebSocket(requestID string, request *borepb.Request, response *borepb.Response, requestedAt time.Time) {
+	requestTimestamp := requestedAt.UnixMilli()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.logs[requestID] = &Log{
+		RequestID: requestID,
+		Request: &borepb.Request{
+			Method:    request.Method,
+			Path:      request.Path,
+			Headers:   request.Headers,
+			Timestamp: requestTimestamp,
+		},
+		Response: response,
+		Duration: response.Timestamp - requestTimestamp,
+	}
+}
+
+func (l *Logger) recordBody(requestID string, chunk []byte, body func(*Log) *[]byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	log, ok := l.logs[requestID]
+	if !ok {
+		return
+	}
+
+	dst := body(log)
+	room := maxLoggedBodySize - len(*dst)
+	if room <= 0 {
+		return
+	}
+
+	*dst = append(*dst, chunk[:min(room, len(chunk))]...)
+}
+
+// GetLogs returns copies of every captured log, newest first.
 func (l *Logger) GetLogs() []*Log {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	var allLogs []*Log
 	for _, log := range l.logs {
-		allLogs = append(allLogs, log)
+		allLogs = append(allLogs, log.snapshot())
 	}
 
 	sort.Slice(allLogs, func(i, j int) bool {
@@ -109,6 +251,21 @@ func (l *Logger) GetLogs() []*Log {
 	return allLogs
 }
 
+// Save writes every captured log to path as JSON, newest first like GetLogs.
+func (l *Logger) Save(path string) error {
+	data, err := json.MarshalIndent(l.GetLogs(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
 func (l *Logger) GetFilteredLogs(filterQuery string) ([]*Log, error) {
 	parsedFilters, err := ParseQuery(filterQuery)
 	if err != nil {
@@ -138,11 +295,18 @@ func (l *Logger) GetFilteredLogs(filterQuery string) ([]*Log, error) {
 	return filteredLogs, nil
 }
 
+// GetLogByID returns a copy of the log for requestID, or nil if there is
+// none.
 func (l *Logger) GetLogByID(requestID string) *Log {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	return l.logs[requestID]
+	log, ok := l.logs[requestID]
+	if !ok {
+		return nil
+	}
+
+	return log.snapshot()
 }
 
 func (l *Logger) flattenHeaders(headers http.Header) map[string]string {
@@ -154,3 +318,26 @@ func (l *Logger) flattenHeaders(headers http.Header) map[string]string {
 
 	return headersMap
 }
+
+// bodyRecorder copies a body into the log as it is streamed to its reader.
+type bodyRecorder struct {
+	reader io.Reader
+	record func([]byte)
+}
+
+func (b *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if n > 0 {
+		b.record(p[:n])
+	}
+
+	return n, err
+}
+
+func (b *bodyRecorder) Close() error {
+	if closer, ok := b.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
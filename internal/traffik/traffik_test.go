@@ -0,0 +1,54 @@
+package traffik
+
+import (
+	borepb "bore/borepb"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestLogsAreCopiesOfTheRecordedLogs(t *testing.T) {
+	l := NewLogger()
+	l.LogBlocked(&borepb.Blocked{Id: "a", Method: "POST", Path: "/upload"})
+
+	log := l.GetLogByID("a")
+	l.recordBody("a", []byte("body"), func(log *Log) *[]byte { return &log.Request.Body })
+	l.LogCancelled("a", "visitor went away")
+
+	if len(log.Request.Body) != 0 || log.Cancelled {
+		t.Fatalf("want the earlier copy unchanged, got body %q and cancelled=%v", log.Request.Body, log.Cancelled)
+	}
+
+	log = l.GetLogs()[0]
+	if string(log.Request.Body) != "body" || !log.Cancelled {
+		t.Fatalf("want a fresh copy to see the update, got body %q and cancelled=%v", log.Request.Body, log.Cancelled)
+	}
+
+	if l.GetLogByID("missing") != nil {
+		t.Fatal("want nil for a missing log")
+	}
+}
+
+// TestReadingLogsWhileRecording is most useful with -race.
+func TestReadingLogsWhileRecording(t *testing.T) {
+	l := NewLogger()
+	l.LogBlocked(&borepb.Blocked{Id: "a", Method: "POST", Path: "/upload"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 1000 {
+			l.recordBody("a", []byte("chunk"), func(log *Log) *[]byte { return &log.Response.Body })
+		}
+		l.LogCancelled("a", "visitor went away")
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			json.Marshal(l.GetLogs())
+			json.Marshal(l.GetLogByID("a"))
+		}
+	}()
+	wg.Wait()
+}
@@ -20,6 +20,7 @@ type model struct {
 	height      int
 	logger      *traffik.Logger
 	appURL      string
+	urlCh       <-chan string
 	filterMode  bool
 	filterQuery string
 	cursorPos   int
@@ -51,6 +52,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	default:
 	}
 
+	select {
+	case appURL := <-m.urlCh:
+		m.appURL = appURL
+	default:
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if m.filterMode {
@@ -317,6 +324,10 @@ func logsToRows(logs []*traffik.Log) []table.Row {
 			}
 		}
 
+		if log.Cancelled {
+			status = "cancelled"
+		}
+
 		respTime = fmt.Sprintf("%d", log.Duration)
 		rows = append(rows, table.Row{requestID, method, uri, status, contentType, size, respTime})
 	}
@@ -502,6 +513,16 @@ func (m *model) renderLogDetails() string {
 		statusValue := lipgloss.NewStyle().Foreground(lipgloss.Color(statusColor)).Bold(true).Render(fmt.Sprintf("%d", res.StatusCode))
 		content.WriteString(renderKV("Status Code", statusValue, 0))
 
+		if res.Error != "" {
+			errorValue := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(res.Error)
+			content.WriteString(renderKV("Error", errorValue, 0))
+		}
+
+		if log.Cancelled {
+			cancelledValue := lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Render("cancelled by bore server")
+			content.WriteString(renderKV("Cancelled", cancelledValue, 0))
+		}
+
 		// Response Timestamp
 		if res.Timestamp > 0 {
 			timestamp := time.UnixMilli(res.Timestamp).Format("2006-01-02 15:04:05.000")
@@ -554,7 +575,7 @@ func (m *model) renderLogDetails() string {
 	return content.String()
 }
 
-func NewModel(logger *traffik.Logger, appURL string, portCh <-chan int) model {
+func NewModel(logger *traffik.Logger, appURL string, urlCh <-chan string, portCh <-chan int) model {
 	columns := getColumns(80)
 
 	var rows []table.Row
@@ -595,6 +616,7 @@ func NewModel(logger *traffik.Logger, appURL string, portCh <-chan int) model {
 		table:    t,
 		logger:   logger,
 		appURL:   appURL,
+		urlCh:    urlCh,
 		viewport: vp,
 		portCh:   portCh,
 	}
@@ -2,11 +2,13 @@ package web
 
 import (
 	"bore/internal/traffik"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"path/filepath"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -17,6 +19,9 @@ type WebServer struct {
 	Traffik *traffik.Logger
 	Port    int
 	PortCh  chan<- int
+	server  *http.Server
+	closed  bool
+	mutex   sync.Mutex
 }
 
 func (ws *WebServer) StartServer() error {
@@ -55,6 +60,7 @@ func (ws *WebServer) StartServer() error {
 					"status_code": log.Response.StatusCode,
 					"timestamp":   log.Response.Timestamp,
 				},
+				"Cancelled": log.Cancelled,
 			}
 		}
 
@@ -118,7 +124,18 @@ func (ws *WebServer) StartServer() error {
 		if err == nil {
 			ws.PortCh <- ws.Port
 			close(ws.PortCh)
-			return http.Serve(netListerner, router)
+
+			ws.mutex.Lock()
+			if ws.closed {
+				ws.mutex.Unlock()
+				netListerner.Close()
+				return http.ErrServerClosed
+			}
+			server := &http.Server{Handler: router}
+			ws.server = server
+			ws.mutex.Unlock()
+
+			return server.Serve(netListerner)
 		}
 
 		ws.Port++
@@ -126,3 +143,18 @@ func (ws *WebServer) StartServer() error {
 
 	return fmt.Errorf("failed to start web server after %d retries", maxRetries)
 }
+
+// Shutdown stops the web server, letting open requests finish until ctx is
+// done. Once called, StartServer won't start the server anymore.
+func (ws *WebServer) Shutdown(ctx context.Context) error {
+	ws.mutex.Lock()
+	ws.closed = true
+	server := ws.server
+	ws.mutex.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	return server.Shutdown(ctx)
+}